@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mstarongithub/way2gay/config"
+	"github.com/sirupsen/logrus"
+	"github.com/swaywm/go-wlroots/wlroots"
+	"github.com/swaywm/go-wlroots/xkb"
+)
+
+// Linux input event codes of mouse buttons
+const (
+	BTN_LEFT   = 0x110
+	BTN_RIGHT  = 0x111
+	BTN_MIDDLE = 0x112
+)
+
+// A key together with modifiers that runs a compositor command
+type KeyBinding struct {
+	modifiers wlroots.KeyboardModifier
+	sym       xkb.KeySym
+	command   string // Same as in the repl
+}
+
+// A mouse button together with modifiers that starts dragging a window
+type PointerBinding struct {
+	modifiers wlroots.KeyboardModifier
+	button    uint32
+}
+
+// Modifiers by their lowercase name in the config
+var modifierNames = map[string]wlroots.KeyboardModifier{
+	"shift":   wlroots.KeyboardModifierShift,
+	"control": wlroots.KeyboardModifierCtrl,
+	"ctrl":    wlroots.KeyboardModifierCtrl,
+	"alt":     wlroots.KeyboardModifierAlt,
+	"mod1":    wlroots.KeyboardModifierAlt,
+	"logo":    wlroots.KeyboardModifierLogo,
+	"super":   wlroots.KeyboardModifierLogo,
+	"meta":    wlroots.KeyboardModifierLogo,
+	"mod4":    wlroots.KeyboardModifierLogo,
+}
+
+// Mouse buttons by their name in the config
+var buttonNames = map[string]uint32{
+	"left":   BTN_LEFT,
+	"right":  BTN_RIGHT,
+	"middle": BTN_MIDDLE,
+}
+
+// Modifiers that are locked instead of held, they don't matter for bindings
+const LOCK_MODIFIERS = wlroots.KeyboardModifierCaps | wlroots.KeyboardModifierMod2
+
+// Combine modifiers given by their names in the config
+// Empty names are ignored
+func parseModifiers(names []string) (wlroots.KeyboardModifier, error) {
+	modifiers := wlroots.KeyboardModifier(0)
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		modifier, ok := modifierNames[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown modifier \"%s\"", name)
+		}
+		modifiers |= modifier
+	}
+	return modifiers, nil
+}
+
+// Parse the binding resizing tiled windows from the pointer config
+func parseResizeBinding(pointer config.ConfigPointer) (PointerBinding, error) {
+	names := pointer.ResizeModifiers
+	if names == nil {
+		names = []string{config.DEFAULT_BASE_KEY}
+	}
+	modifiers, err := parseModifiers(names)
+	if err != nil {
+		return PointerBinding{}, err
+	}
+	button := uint32(BTN_RIGHT)
+	if pointer.ResizeButton != "" {
+		var ok bool
+		if button, ok = buttonNames[strings.ToLower(pointer.ResizeButton)]; !ok {
+			return PointerBinding{}, fmt.Errorf("unknown button \"%s\", expected one of left, right or middle", pointer.ResizeButton)
+		}
+	}
+	return PointerBinding{modifiers: modifiers, button: button}, nil
+}
+
+// Get the binding resizing tiled windows from the pointer config
+// Falls back to the default binding with a warning if it is invalid
+func resizeBindingFromConfig(pointer config.ConfigPointer) PointerBinding {
+	binding, err := parseResizeBinding(pointer)
+	if err != nil {
+		logrus.WithError(err).Warnln("Invalid resize binding in config, using the default")
+		binding, _ = parseResizeBinding(config.ConfigPointer{})
+	}
+	return binding
+}
+
+// Whether pressing a button while holding the given modifiers triggers the binding
+func (binding PointerBinding) matches(modifiers wlroots.KeyboardModifier, button uint32) bool {
+	return button == binding.button && modifiers&^LOCK_MODIFIERS == binding.modifiers
+}
+
+// Turn the commands of the config into key bindings, in the order of their names
+// Unset commands use the default bindings. Invalid ones get skipped with a warning
+func keyBindingsFromConfig(commands map[string]config.ConfigCommand) []KeyBinding {
+	if commands == nil {
+		commands = config.DEFAULT_CONFIG.Commands
+	}
+	bindings := []KeyBinding{}
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		parsed, err := parseKeyBinding(commands[name])
+		if err != nil {
+			logrus.WithError(err).WithField("command", name).Warnln("Invalid key binding in config, skipping it")
+			continue
+		}
+		bindings = append(bindings, parsed...)
+	}
+	return bindings
+}
+
+// Parse a command of the config into one binding per key
+func parseKeyBinding(command config.ConfigCommand) ([]KeyBinding, error) {
+	if command.Action == "" {
+		return nil, errors.New("no action")
+	}
+	if len(command.ActionKeys) == 0 {
+		return nil, errors.New("no keys")
+	}
+	modifiers, err := parseModifiers(append([]string{command.BaseKey}, command.ModKeys...))
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]KeyBinding, 0, len(command.ActionKeys))
+	for _, key := range command.ActionKeys {
+		sym := xkb.SymFromName(key, xkb.KeySymFlagNoFlags)
+		if sym == xkb.KeySymNoSymbol {
+			return nil, fmt.Errorf("unknown key \"%s\"", key)
+		}
+		bindings = append(bindings, KeyBinding{modifiers: modifiers, sym: sym, command: command.Action})
+	}
+	return bindings, nil
+}
+
+// Run the command bound to a pressed key
+// Bindings match the keysyms the key produces with the held modifiers or the one it produces without any,
+// so Shift+1 can be bound as 1 on any layout
+// Returns false if nothing is bound to the key
+func (server *Server) runKeyBinding(modifiers wlroots.KeyboardModifier, syms []xkb.KeySym, unmodified xkb.KeySym) bool {
+	modifiers &^= LOCK_MODIFIERS
+	for _, binding := range server.keyBindings {
+		if binding.modifiers != modifiers || (binding.sym != unmodified && !slices.Contains(syms, binding.sym)) {
+			continue
+		}
+		result, known := server.runCommand(binding.command)
+		if !known {
+			logrus.WithField("command", binding.command).Warnln("Key bound to unknown command")
+		} else {
+			logrus.WithFields(logrus.Fields{
+				"command": binding.command,
+				"result":  result,
+			}).Debugln("Ran key binding")
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mstarongithub/way2gay/config"
+	"github.com/mstarongithub/way2gay/tiler"
+	"github.com/mstarongithub/way2gay/util"
+)
+
+// Run a compositor command, shared by keybindings and the repl
+// Returns a human readable result and whether the command is known at all
+func (server *Server) runCommand(input string) (string, bool) {
+	command, args, _ := strings.Cut(strings.TrimSpace(input), " ")
+	switch command {
+	case "focus":
+		if mark, ok := strings.CutPrefix(args, "mark "); ok {
+			if !server.focusMark(mark) {
+				return fmt.Sprintf("No window marked \"%s\"", mark), true
+			}
+			return "Focused " + mark, true
+		}
+		side, err := parseSide(args)
+		if err != nil {
+			return err.Error(), true
+		}
+		if !server.focusSide(side) {
+			return "No window in that direction", true
+		}
+		return "Focused " + args, true
+	case "workspace":
+		// workspace <name>, numbers are names too, or workspace back-and-forth
+		if args == "" {
+			return "Expected a workspace", true
+		}
+		if !server.switchWorkspace(args) {
+			return "Already on workspace " + server.workspace.name, true
+		}
+		return "Switched to workspace " + server.workspace.name, true
+	case "move", "swap":
+		if name, ok := strings.CutPrefix(args, "workspace "); ok && command == "move" {
+			window := server.focusedWindow()
+			if window == nil {
+				return "No focused window", true
+			}
+			if !server.moveToWorkspace(window, name) {
+				return "Window is on that workspace already", true
+			}
+			return "Moved window to workspace " + window.workspace.name, true
+		}
+		if mark, ok := strings.CutPrefix(args, "mark "); ok {
+			return server.moveToMark(mark, command == "swap"), true
+		}
+		side, err := parseSide(args)
+		if err != nil {
+			return err.Error(), true
+		}
+		if !server.moveSide(side, command == "swap") {
+			return "No window in that direction", true
+		}
+		return "Moved " + args, true
+	case "preselect":
+		// preselect <side> [ratio] or preselect cancel
+		var rawSide, rawRatio string
+		util.Unpack(strings.Fields(args), &rawSide, &rawRatio)
+		if rawSide == "cancel" {
+			if !server.tree.CancelPreselection() {
+				return "Nothing preselected", true
+			}
+			server.updatePreselectionPreview()
+			return "Canceled preselection", true
+		}
+		side, err := parseSide(rawSide)
+		if err != nil {
+			return err.Error(), true
+		}
+		ratio := 0
+		if rawRatio != "" {
+			if ratio, err = strconv.Atoi(strings.TrimSuffix(rawRatio, "%")); err != nil {
+				return fmt.Sprintf("invalid ratio \"%s\"", rawRatio), true
+			}
+		}
+		if !server.tree.Preselect(side, ratio) {
+			return "Can't preselect with an automatic layout", true
+		}
+		server.updatePreselectionPreview()
+		return "Preselected " + rawSide, true
+	case "mark":
+		if args == "" {
+			return "Expected a mark", true
+		}
+		window := server.focusedWindow()
+		if window == nil {
+			return "No focused window", true
+		}
+		server.markWindow(window, args)
+		return "Marked " + args, true
+	case "unmark":
+		if !server.unmark(args) {
+			return fmt.Sprintf("No window marked \"%s\"", args), true
+		}
+		return "Unmarked " + args, true
+	case "resize":
+		// resize <grow|shrink> <side> <amount>[px|%]
+		var mode, rawSide, rawAmount string
+		util.Unpack(strings.Fields(args), &mode, &rawSide, &rawAmount)
+		side, err := parseSide(rawSide)
+		if err != nil {
+			return err.Error(), true
+		}
+		amount, percent, err := parseAmount(rawAmount)
+		if err != nil {
+			return err.Error(), true
+		}
+		switch mode {
+		case "grow":
+		case "shrink":
+			amount = -amount
+		default:
+			return fmt.Sprintf("unknown resize mode \"%s\", expected grow or shrink", mode), true
+		}
+		if !server.resizeFocused(side, amount, percent) {
+			return "Can't resize in that direction", true
+		}
+		return "Resized " + rawSide, true
+	case "container":
+		mode, err := parseContainerMode(args)
+		if err != nil {
+			return err.Error(), true
+		}
+		if !server.setContainerMode(mode) {
+			return "Focused window isn't in a container", true
+		}
+		return "Switched container to " + args, true
+	case "tab":
+		if args != "next" && args != "prev" {
+			return fmt.Sprintf("unknown tab direction \"%s\", expected next or prev", args), true
+		}
+		if !server.cycleTab(args == "next") {
+			return "Focused window isn't in a tabbed or stacked container", true
+		}
+		return "Switched to " + args + " tab", true
+	case "layout":
+		if args == "" {
+			return "Current layout is " + server.tree.LayoutName(), true
+		}
+		if err := server.setLayout(args); err != nil {
+			return err.Error(), true
+		}
+		return "Switched layout to " + args, true
+	case "master":
+		// master <count|ratio> <change>
+		var setting, rawChange string
+		util.Unpack(strings.Fields(args), &setting, &rawChange)
+		change, err := strconv.Atoi(strings.TrimSuffix(rawChange, "%"))
+		if err != nil {
+			return fmt.Sprintf("invalid change \"%s\", expected a number like +1 or -5", rawChange), true
+		}
+		var adjusted bool
+		switch setting {
+		case "count":
+			adjusted = server.adjustMaster(change, 0)
+		case "ratio":
+			adjusted = server.adjustMaster(0, change)
+		default:
+			return fmt.Sprintf("unknown master setting \"%s\", expected count or ratio", setting), true
+		}
+		if !adjusted {
+			return "Not using the master-stack layout", true
+		}
+		return "Changed master " + setting + " by " + rawChange, true
+	case "gaps":
+		// gaps inner <amount>, gaps outer [top|right|bottom|left] <amount> or gaps smart <on|off>
+		// Amounts starting with + or - change the current gaps instead of replacing them
+		fields := strings.Fields(args)
+		if len(fields) == 0 {
+			gaps := server.tree.Gaps()
+			return fmt.Sprintf(
+				"Gaps: inner %d, outer %d %d %d %d (top right bottom left), smart %v",
+				gaps.Inner, gaps.OuterTop, gaps.OuterRight, gaps.OuterBottom, gaps.OuterLeft, gaps.Smart,
+			), true
+		}
+		gaps := server.tree.Gaps()
+		switch fields[0] {
+		case "inner":
+			if len(fields) != 2 {
+				return "expected gaps inner <amount>", true
+			}
+			if err := applyGapAmount(fields[1], &gaps.Inner); err != nil {
+				return err.Error(), true
+			}
+		case "outer":
+			targets := []*int{&gaps.OuterTop, &gaps.OuterRight, &gaps.OuterBottom, &gaps.OuterLeft}
+			if len(fields) == 3 {
+				target, ok := map[string]*int{
+					"top":    &gaps.OuterTop,
+					"right":  &gaps.OuterRight,
+					"bottom": &gaps.OuterBottom,
+					"left":   &gaps.OuterLeft,
+				}[fields[1]]
+				if !ok {
+					return fmt.Sprintf("unknown side \"%s\", expected one of top, right, bottom, left", fields[1]), true
+				}
+				targets = []*int{target}
+			} else if len(fields) != 2 {
+				return "expected gaps outer [side] <amount>", true
+			}
+			for _, target := range targets {
+				if err := applyGapAmount(fields[len(fields)-1], target); err != nil {
+					return err.Error(), true
+				}
+			}
+		case "smart":
+			switch strings.Join(fields[1:], " ") {
+			case "on":
+				gaps.Smart = true
+			case "off":
+				gaps.Smart = false
+			default:
+				return "expected gaps smart <on|off>", true
+			}
+		default:
+			return fmt.Sprintf("unknown gaps setting \"%s\", expected inner, outer or smart", fields[0]), true
+		}
+		server.setGaps(gaps)
+		return "Changed gaps", true
+	case "rotate":
+		// rotate <cw|ccw> [root]
+		var rawDirection, rawScope string
+		util.Unpack(strings.Fields(args), &rawDirection, &rawScope)
+		scope, err := parseScope(rawScope)
+		if err != nil {
+			return err.Error(), true
+		}
+		if rawDirection != "cw" && rawDirection != "ccw" {
+			return fmt.Sprintf("unknown rotation \"%s\", expected cw or ccw", rawDirection), true
+		}
+		if !server.tree.Rotate(scope, rawDirection == "cw") {
+			return "Nothing to rotate", true
+		}
+		return "Rotated " + rawDirection, true
+	case "flip":
+		// flip <horizontal|vertical> [root]
+		var rawDirection, rawScope string
+		util.Unpack(strings.Fields(args), &rawDirection, &rawScope)
+		scope, err := parseScope(rawScope)
+		if err != nil {
+			return err.Error(), true
+		}
+		var direction tiler.Direction
+		switch rawDirection {
+		case "horizontal":
+			direction = tiler.DirectionHorizontal
+		case "vertical":
+			direction = tiler.DirectionVertical
+		default:
+			return fmt.Sprintf("unknown flip \"%s\", expected horizontal or vertical", rawDirection), true
+		}
+		if !server.tree.Flip(scope, direction) {
+			return "Nothing to flip", true
+		}
+		return "Flipped " + rawDirection, true
+	case "equalize":
+		// equalize [root]
+		scope, err := parseScope(args)
+		if err != nil {
+			return err.Error(), true
+		}
+		if !server.tree.Equalize(scope) {
+			return "Nothing to equalize", true
+		}
+		return "Equalized", true
+	case "toggle-split":
+		if !server.tree.ToggleSplit() {
+			return "Focused window isn't split", true
+		}
+		return "Toggled split", true
+	case "undo":
+		if !server.tree.Undo() {
+			return "Nothing to undo", true
+		}
+		return "Undid last change", true
+	case "redo":
+		if !server.tree.Redo() {
+			return "Nothing to redo", true
+		}
+		return "Redid last change", true
+	case "fullscreen", "maximize":
+		state := WindowStateFullscreen
+		if command == "maximize" {
+			state = WindowStateMaximized
+		}
+		if !server.toggleWindowState(state) {
+			return "No focused window", true
+		}
+		return "Toggled " + command, true
+	case "floating":
+		if !server.toggleFloating() {
+			return "No focused window", true
+		}
+		return "Toggled floating", true
+	case "sticky":
+		window, ok := server.toggleSticky()
+		switch {
+		case window == nil:
+			return "No focused window", true
+		case !ok:
+			return "Only floating windows can be sticky", true
+		case window.sticky:
+			return "Window is sticky now", true
+		default:
+			return "Window isn't sticky anymore", true
+		}
+	case "scratchpad":
+		switch args {
+		case "move":
+			window := server.focusedWindow()
+			if window == nil {
+				return "No focused window", true
+			}
+			server.moveToScratchpad(window)
+			return "Moved window to the scratchpad", true
+		case "show":
+			if !server.toggleScratchpad() {
+				return "Scratchpad is empty", true
+			}
+			return "Toggled scratchpad", true
+		default:
+			return fmt.Sprintf("unknown scratchpad action \"%s\", expected move or show", args), true
+		}
+	case "save-layout":
+		if err := config.WriteFile(args, server.tree.ExportLayout()); err != nil {
+			return err.Error(), true
+		}
+		return "Saved layout to " + args, true
+	case "load-layout":
+		layout := tiler.LayoutNode{}
+		if err := config.ReadFile(args, &layout); err != nil {
+			return err.Error(), true
+		}
+		if err := server.tree.ImportLayout(layout); err != nil {
+			return fmt.Sprintf("Invalid layout %s: %s", args, err), true
+		}
+		return "Loaded layout from " + args, true
+	case "append-layout":
+		if err := server.appendLayoutFile(args); err != nil {
+			return err.Error(), true
+		}
+		return "Appended layout from " + args, true
+	default:
+		return "", false
+	}
+}
+
+// Parse an amount given in pixels ("20px" or just "20") or percent ("5%")
+func parseAmount(raw string) (int, bool, error) {
+	number, percent := strings.CutSuffix(raw, "%")
+	if !percent {
+		number = strings.TrimSuffix(raw, "px")
+	}
+	amount, err := strconv.Atoi(number)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid amount \"%s\", expected pixels (20px) or percent (5%%)", raw)
+	}
+	return amount, percent, nil
+}
+
+// Set a gap to an amount of pixels, or change it if the amount starts with + or -
+func applyGapAmount(raw string, gap *int) error {
+	amount, err := strconv.Atoi(strings.TrimSuffix(raw, "px"))
+	if err != nil {
+		return fmt.Errorf("invalid gap \"%s\", expected pixels like 10, +5 or -5", raw)
+	}
+	if strings.HasPrefix(raw, "+") || strings.HasPrefix(raw, "-") {
+		*gap += amount
+	} else {
+		*gap = amount
+	}
+	return nil
+}
+
+// Parse the optional scope of a restructuring command
+// Empty means the split around the focused window, "root" the whole tree
+func parseScope(raw string) (tiler.Scope, error) {
+	switch raw {
+	case "":
+		return tiler.ScopeParent, nil
+	case "root":
+		return tiler.ScopeRoot, nil
+	default:
+		return tiler.ScopeParent, fmt.Errorf("unknown scope \"%s\", expected root or nothing", raw)
+	}
+}
+
+// Parse a side (left, right, up, down) given in a command
+func parseSide(raw string) (tiler.Side, error) {
+	switch raw {
+	case "up":
+		return tiler.SideUp, nil
+	case "down":
+		return tiler.SideDown, nil
+	case "left":
+		return tiler.SideLeft, nil
+	case "right":
+		return tiler.SideRight, nil
+	default:
+		return tiler.SideUp, fmt.Errorf("unknown direction \"%s\", expected one of up, down, left, right", raw)
+	}
+}
+
+// Parse a container mode (split, tabbed, stacked) given in a command
+func parseContainerMode(raw string) (tiler.ContainerMode, error) {
+	switch raw {
+	case "split":
+		return tiler.ContainerModeSplit, nil
+	case "tabbed":
+		return tiler.ContainerModeTabbed, nil
+	case "stacked":
+		return tiler.ContainerModeStacked, nil
+	default:
+		return tiler.ContainerModeSplit, fmt.Errorf("unknown container mode \"%s\", expected one of split, tabbed, stacked", raw)
+	}
+}
@@ -22,8 +22,14 @@ type (
 		// Screen config
 		Screens map[string]ConfigScreen `json:"screens" toml:"screens" yaml:"screens"` // Per screen config. Missing screens will use their preferred mode. Key is screen name
 
+		// Tiling config
+		Tiling ConfigTiling `json:"tiling" toml:"tiling" yaml:"tiling"`
+
+		// Pointer config
+		Pointer ConfigPointer `json:"pointer" toml:"pointer" yaml:"pointer"`
+
 		// Commands
-		Commands map[string]ConfigCommand `json:"commands" toml:"commands" yaml:"commands"` // All the commands, key is command name
+		Commands map[string]ConfigCommand `json:"commands" toml:"commands" yaml:"commands"` // All the commands, key is command name. Unset uses the default bindings, an empty map binds nothing
 		OnStart  ConfigStartup            `json:"startup" toml:"startup" yaml:"startup"`    // Things to do on start
 	}
 	ConfigStartup struct {
@@ -32,7 +38,31 @@ type (
 	}
 
 	ConfigTiling struct {
-		SplitToLeft bool `json:"split_left" toml:"split_left" yaml:"split_left"` // When splitting a leaf, should the original leaf be on the left or the right. True if left, false if right
+		SplitToLeft *bool         `json:"split_left" toml:"split_left" yaml:"split_left"`       // When splitting a leaf, should the original leaf be on the left or the right. True if left, false if right. Unset means left. Preselecting a side overrides it for the next window
+		Layout      string        `json:"layout" toml:"layout" yaml:"layout"`                   // Layout workspaces start with. One of tree, master-stack, monocle, grid, dwindle or spiral. Empty means tree
+		MasterCount int           `json:"master_count" toml:"master_count" yaml:"master_count"` // Amount of windows in the master area of the master-stack layout. 0 uses the default of 1
+		MasterRatio int           `json:"master_ratio" toml:"master_ratio" yaml:"master_ratio"` // Percentage of the width taken up by the master area of the master-stack layout. 0 uses the default of 55
+		Gaps        ConfigGaps    `json:"gaps" toml:"gaps" yaml:"gaps"`                         // Empty space around tiled windows
+		Template    string        `json:"template" toml:"template" yaml:"template"`             // Layout file added to the tree on start. Its placeholders get filled by the first matching windows
+		Swallow     ConfigSwallow `json:"swallow" toml:"swallow" yaml:"swallow"`                // Windows taking over the place of the terminal they were started from
+	}
+	ConfigSwallow struct {
+		Enabled   bool     `json:"enabled" toml:"enabled" yaml:"enabled"`       // Whether windows swallow terminals at all
+		Terminals []string `json:"terminals" toml:"terminals" yaml:"terminals"` // App IDs of terminals that can be swallowed
+		Deny      []string `json:"deny" toml:"deny" yaml:"deny"`                // App IDs of windows that never swallow a terminal, for example other terminals
+	}
+	ConfigGaps struct {
+		Inner  int  `json:"inner" toml:"inner" yaml:"inner"`    // Pixels between two windows
+		Outer  int  `json:"outer" toml:"outer" yaml:"outer"`    // Pixels between the windows and the screen border
+		Top    *int `json:"top" toml:"top" yaml:"top"`          // Overrides the outer gap on the top side if set
+		Right  *int `json:"right" toml:"right" yaml:"right"`    // Overrides the outer gap on the right side if set
+		Bottom *int `json:"bottom" toml:"bottom" yaml:"bottom"` // Overrides the outer gap on the bottom side if set
+		Left   *int `json:"left" toml:"left" yaml:"left"`       // Overrides the outer gap on the left side if set
+		Smart  bool `json:"smart" toml:"smart" yaml:"smart"`    // Leave out all gaps while there is only a single window
+	}
+	ConfigPointer struct {
+		ResizeModifiers []string `json:"resize_modifiers" toml:"resize_modifiers" yaml:"resize_modifiers"` // Modifier keys to hold while dragging a tiled window to resize it, for example Logo or Alt. Unset uses DEFAULT_BASE_KEY
+		ResizeButton    string   `json:"resize_button" toml:"resize_button" yaml:"resize_button"`          // Mouse button dragging with the resize modifiers, one of left, right or middle. Empty means right
 	}
 	ConfigScreen struct {
 		Resolution  string  `json:"resolution" toml:"resolution" yaml:"resolution"`       // Resolution the screen will run at (format is "<width>x<height>") (Resolution before Scaler is applied)
@@ -44,8 +74,8 @@ type (
 	ConfigCommand struct {
 		BaseKey    string   `json:"base" toml:"base" yaml:"base"`                // Main key, for example Meta
 		ModKeys    []string `json:"modifiers" toml:"modifiers" yaml:"modifiers"` // Modifier keys such as Shift, Control, etc
-		ActionKeys []string `json:"keys" toml:"keys" yaml:"keys"`                // Key to trigger that command
-		Action     string   `json:"action" toml:"action" yaml:"action"`          // Command to run
+		ActionKeys []string `json:"keys" toml:"keys" yaml:"keys"`                // Keys to trigger that command, any of them does. Names as in xkbcommon, for example h, Tab or space
+		Action     string   `json:"action" toml:"action" yaml:"action"`          // Command to run, same as in the repl
 		// TODO: Make another struct for actions
 		// One for executing an app
 		// One for w2g actions
@@ -55,10 +85,52 @@ type (
 // TODO: Fill in sane default values
 var DEFAULT_CONFIG = Config{
 	Screens:  map[string]ConfigScreen{},
-	Commands: map[string]ConfigCommand{},
+	Tiling:   ConfigTiling{Layout: "tree"},
+	Commands: defaultCommands(),
 	OnStart:  ConfigStartup{},
 }
 
+// Modifier all default bindings are on, so they don't take keys away from clients using Alt
+const DEFAULT_BASE_KEY = "Logo"
+
+// Build the default key bindings
+func defaultCommands() map[string]ConfigCommand {
+	bind := func(key, action string, modifiers ...string) ConfigCommand {
+		return ConfigCommand{BaseKey: DEFAULT_BASE_KEY, ModKeys: modifiers, ActionKeys: []string{key}, Action: action}
+	}
+	commands := map[string]ConfigCommand{
+		"focus-left":        bind("h", "focus left"),
+		"focus-down":        bind("j", "focus down"),
+		"focus-up":          bind("k", "focus up"),
+		"focus-right":       bind("l", "focus right"),
+		"move-left":         bind("h", "move left", "Shift"),
+		"move-down":         bind("j", "move down", "Shift"),
+		"move-up":           bind("k", "move up", "Shift"),
+		"move-right":        bind("l", "move right", "Shift"),
+		"container-split":   bind("e", "container split"),
+		"container-tabbed":  bind("w", "container tabbed"),
+		"container-stacked": bind("s", "container stacked"),
+		"tab-next":          bind("Tab", "tab next"),
+		"rotate":            bind("r", "rotate cw"),
+		"toggle-split":      bind("t", "toggle-split"),
+		"equalize":          bind("b", "equalize"),
+		"fullscreen":        bind("f", "fullscreen"),
+		"maximize":          bind("m", "maximize"),
+		"floating":          bind("space", "floating"),
+		"scratchpad-show":   bind("minus", "scratchpad show"),
+		"scratchpad-move":   bind("minus", "scratchpad move", "Shift"),
+		"undo":              bind("u", "undo"),
+		"redo":              bind("u", "redo", "Shift"),
+		"workspace-back":    bind("grave", "workspace back-and-forth"),
+	}
+	for number := 1; number <= 9; number++ {
+		key := fmt.Sprint(number)
+		commands["workspace-"+key] = bind(key, "workspace "+key)
+		commands["move-workspace-"+key] = bind(key, "move workspace "+key, "Shift")
+	}
+	return commands
+}
+
 // Parse a given config file
 // Will fall back to first system default, then built-in default if the given file is not found or cannot be parsed
 // Always returns a valid config, error will be set if it has to fall back
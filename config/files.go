@@ -0,0 +1,65 @@
+// Copyright (c) 2024 mStar
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Read a file in one of the formats configs can be written in and unmarshal it into target
+// The format is picked from the file extension: toml (also used without extension), json, yaml or yml
+func ReadFile(file string, target any) error {
+	_, unmarshal, err := formatForFile(file)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	if err = unmarshal(content, target); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return nil
+}
+
+// Marshal source and write it to a file
+// Uses the same format selection as ReadFile
+func WriteFile(file string, source any) error {
+	marshal, _, err := formatForFile(file)
+	if err != nil {
+		return err
+	}
+	content, err := marshal(source)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", file, err)
+	}
+	if err = os.WriteFile(file, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
+
+// Get the marshal and unmarshal funcs for the format of a file
+func formatForFile(file string) (func(any) ([]byte, error), func([]byte, any) error, error) {
+	switch ext := filepath.Ext(file); ext {
+	case ".toml", "":
+		return toml.Marshal, toml.Unmarshal, nil
+	case ".json":
+		return func(v any) ([]byte, error) { return json.MarshalIndent(v, "", "  ") }, json.Unmarshal, nil
+	case ".yaml", ".yml":
+		return yaml.Marshal, yaml.Unmarshal, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown file extension %s", ext)
+	}
+}
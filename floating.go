@@ -0,0 +1,84 @@
+package main
+
+import (
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+	"github.com/mstarongithub/way2gay/tiler"
+	"github.com/swaywm/go-wlroots/wlroots"
+)
+
+// Check if a toplevel should start out floating instead of tiled
+// Toplevels with a parent or a fixed size are dialogs
+func isDialog(topLevel wlroots.XDGTopLevel) bool {
+	if !topLevel.Parent().Nil() {
+		return true
+	}
+	hints := sizeHints(topLevel)
+	return hints.Min != generaldata.Vector2i{} && hints.Min == hints.Max
+}
+
+// Get the area of a window relative to the first output, as it is currently shown
+func (server *Server) windowArea(window *Window) generaldata.Rect {
+	node := window.topLevel.Base().SceneTree().Node()
+	box := window.topLevel.Base().Geometry()
+	return generaldata.Rect{
+		Position: server.outputRelative(float64(node.X()), float64(node.Y())),
+		Size:     generaldata.Vector2i{X: box.Width, Y: box.Height},
+	}
+}
+
+// Take a window out of the tiling tree and let it float above the tiled windows, or put it back into the tree
+// Floating windows keep the area they had while tiled, new dialogs get centered above their parent. Marks stay with the window
+func (server *Server) setFloating(window *Window, floating bool) {
+	if window.floating == floating {
+		return
+	}
+	window.floating = floating
+	// Scratchpad and sticky windows only make sense while floating
+	server.removeFromScratchpad(window)
+	server.setSticky(window, false)
+	if floating {
+		window.floatingArea = server.windowArea(window)
+		window.marks = window.workspace.tree.MarksOf(window.id)
+		window.workspace.tree.RemoveApp(window.id, true)
+	} else {
+		window.workspace.tree.Update(func(tx *tiler.Transaction) {
+			tx.AddApp(window.id, window.topLevel.AppId())
+			for _, mark := range window.marks {
+				tx.Mark(window.id, mark)
+			}
+		})
+		window.marks = nil
+	}
+	node := window.topLevel.Base().SceneTree().Node()
+	node.Reparent(server.windowLayer(window))
+	node.RaiseToTop()
+	server.arrangeTree()
+}
+
+// Float a newly mapped dialog, centered above its parent or the output if it has no known parent
+func (server *Server) floatDialog(window *Window) {
+	size := server.windowArea(window).Size
+	if size.X <= 0 || size.Y <= 0 {
+		// Client didn't ask for a size yet, give it half the output
+		size = generaldata.Vector2i{X: server.tree.Resolution.X / 2, Y: server.tree.Resolution.Y / 2}
+	}
+	around := generaldata.Rect{Size: server.tree.Resolution}
+	if parent := server.findWindow(window.topLevel.Parent()); parent != nil {
+		around = server.windowArea(parent)
+	}
+
+	window.floating = true
+	window.floatingArea = around.Centered(size)
+	window.topLevel.Base().SceneTree().Node().Reparent(server.windowLayer(window))
+}
+
+// Toggle whether the focused window is floating
+// Returns false if no window is focused
+func (server *Server) toggleFloating() bool {
+	window := server.focusedWindow()
+	if window == nil {
+		return false
+	}
+	server.setFloating(window, !window.floating)
+	return true
+}
@@ -0,0 +1,19 @@
+package generaldata
+
+// An axis aligned rectangle
+// Position is the top left corner
+type Rect struct {
+	Position Vector2i
+	Size     Vector2i
+}
+
+// Get a rectangle of the given size centered within this one
+func (r Rect) Centered(size Vector2i) Rect {
+	return Rect{
+		Position: Vector2i{
+			X: r.Position.X + (r.Size.X-size.X)/2,
+			Y: r.Position.Y + (r.Size.Y-size.Y)/2,
+		},
+		Size: size,
+	}
+}
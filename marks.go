@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Find the window carrying the given mark, floating or tiled on any workspace
+// Returns nil if no window has the mark
+func (server *Server) findMark(mark string) *Window {
+	for _, window := range server.windows {
+		if window.floating && slices.Contains(window.marks, mark) {
+			return window
+		}
+	}
+	for _, workspace := range server.workspaces {
+		if leaf := workspace.tree.FindMark(mark); leaf != nil {
+			return server.windows[leaf.Window]
+		}
+	}
+	return nil
+}
+
+// Get the marks of a window, in the order they were added
+func (server *Server) marksOf(window *Window) []string {
+	if window.floating {
+		return slices.Clone(window.marks)
+	}
+	return window.workspace.tree.MarksOf(window.id)
+}
+
+// Mark a window, taking the mark away from whichever window had it before
+func (server *Server) markWindow(window *Window, mark string) {
+	if slices.Contains(server.marksOf(window), mark) {
+		return
+	}
+	server.unmark(mark)
+	if window.floating {
+		window.marks = append(window.marks, mark)
+		return
+	}
+	window.workspace.tree.Mark(window.id, mark)
+}
+
+// Remove a mark from whichever window has it
+// Returns false if no window has the mark
+func (server *Server) unmark(mark string) bool {
+	window := server.findMark(mark)
+	if window == nil {
+		return false
+	}
+	if !window.floating {
+		return window.workspace.tree.Unmark(mark)
+	}
+	window.marks = slices.DeleteFunc(window.marks, func(other string) bool {
+		return other == mark
+	})
+	return true
+}
+
+// Focus the window carrying the given mark, switching to its workspace or showing it if it's in the scratchpad
+// Returns false if no window has the mark
+func (server *Server) focusMark(mark string) bool {
+	window := server.findMark(mark)
+	if window == nil {
+		return false
+	}
+	if window.hidden {
+		server.showScratchpad(window)
+		return true
+	}
+	server.showWorkspace(window.workspace)
+	surface := window.topLevel.Base().Surface()
+	server.focusTopLevel(&window.topLevel, &surface)
+	return true
+}
+
+// Move the focused window next to the window carrying the given mark, or swap the two
+// Both windows have to be tiled, since only the tree knows where to put them
+// Moving to a window on another workspace takes the focused window there, swapping only works on the same workspace
+// Returns a message saying what was done or why it couldn't be
+func (server *Server) moveToMark(mark string, swap bool) string {
+	target := server.findMark(mark)
+	window := server.focusedWindow()
+	switch {
+	case target == nil:
+		return fmt.Sprintf("No window marked \"%s\"", mark)
+	case window == nil:
+		return "No focused window"
+	case window == target:
+		return fmt.Sprintf("Focused window is the one marked \"%s\"", mark)
+	case window.floating:
+		return "Focused window is floating"
+	case target.floating:
+		return fmt.Sprintf("Window marked \"%s\" is floating", mark)
+	case swap && target.workspace != window.workspace:
+		return fmt.Sprintf("Window marked \"%s\" is on workspace %s", mark, target.workspace.name)
+	}
+	if target.workspace != window.workspace {
+		server.moveToWorkspace(window, target.workspace.name)
+	}
+	var moved bool
+	if swap {
+		moved = window.workspace.tree.SwapMark(mark)
+	} else {
+		moved = window.workspace.tree.MoveToMark(mark)
+	}
+	if !moved {
+		return fmt.Sprintf("Can't move to \"%s\"", mark)
+	}
+	return "Moved to " + mark
+}
@@ -48,87 +48,105 @@ func replRunner(server *Server) {
 			server.Stop()
 			time.Sleep(time.Second * 5)
 			return "Quitting", errors.New("normal stop")
-		} else if rawCmdString, ok := strings.CutPrefix(input, "inspect "); ok {
-			// Can't unpack slices directly like in Python, so do it this roundabout way
-			var target, mod, args string
-			util.Unpack(strings.SplitN(rawCmdString, " ", 2), &target, &mod, &args)
-			logrus.WithFields(logrus.Fields{
-				"cmd":  target,
-				"mod":  mod,
-				"args": args,
-				"raw":  rawCmdString,
-			}).Debugln("Parsed inspect command")
-			switch target {
-			case "display":
-				return "Display: No usable data", nil
-			case "backend":
-				return "Backend: No usable data", nil
-			case "renderer":
-				return "Backend: No usable data", nil
-			case "allocator":
-				return fmt.Sprintf("Allocator: nil: %v", server.allocator.Nil()), nil
-			case "scene":
-				switch mod {
-				case "layout":
-					return "Scene: Layout: No info available", nil
-				default:
-					return fmt.Sprintf("Scene: Tree: %+v", server.scene.Tree()), nil
-				}
-			case "xdg":
-				switch mod {
-				case "shell":
-					return fmt.Sprintf(
-						"xdg-shell: Version %d, Ping Timeout: %d",
-						server.xdgShell.Version(),
-						server.xdgShell.PingTimeout(),
-					), nil
-				case "grabbed-top-level":
-					switch strings.SplitN(args, " ", 1)[0] {
-					case "app-id":
-					case "base":
-					case "parent":
-					case "title":
+		}
+		// Everything else touches the compositor state, which only the event loop may do
+		var result string
+		if !server.callOnEventLoop(func() {
+			result = server.runReplCommand(input)
+		}) {
+			return "Compositor stopped", errors.New("event loop stopped")
+		}
+		return result, nil
+	})
+}
+
+// Run a repl command other than run and quit, either inspecting the compositor or a compositor command
+// Has to be called from the event loop
+func (server *Server) runReplCommand(input string) string {
+	if rawCmdString, ok := strings.CutPrefix(input, "inspect "); ok {
+		// Can't unpack slices directly like in Python, so do it this roundabout way
+		var target, mod, args string
+		util.Unpack(strings.SplitN(rawCmdString, " ", 2), &target, &mod, &args)
+		logrus.WithFields(logrus.Fields{
+			"cmd":  target,
+			"mod":  mod,
+			"args": args,
+			"raw":  rawCmdString,
+		}).Debugln("Parsed inspect command")
+		switch target {
+		case "display":
+			return "Display: No usable data"
+		case "backend":
+			return "Backend: No usable data"
+		case "renderer":
+			return "Backend: No usable data"
+		case "allocator":
+			return fmt.Sprintf("Allocator: nil: %v", server.allocator.Nil())
+		case "scene":
+			switch mod {
+			case "layout":
+				return "Scene: Layout: No info available"
+			default:
+				return fmt.Sprintf("Scene: Tree: %+v", server.scene.Tree())
+			}
+		case "xdg":
+			switch mod {
+			case "shell":
+				return fmt.Sprintf(
+					"xdg-shell: Version %d, Ping Timeout: %d",
+					server.xdgShell.Version(),
+					server.xdgShell.PingTimeout(),
+				)
+			case "grabbed-top-level":
+				switch strings.SplitN(args, " ", 1)[0] {
+				case "app-id":
+				case "base":
+				case "parent":
+				case "title":
 
-					default:
-					}
 				default:
 				}
-			case "topLevelList":
-			case "cursor":
-				switch mod {
-				case "manager":
-					return fmt.Sprintf("Cursor manager (no useful data): %+v", server.cursorMgr), nil
-				case "mode":
-					switch server.cursorMode {
-					case CursorModeMove:
-						return "Cursor mode: Move", nil
-					case CursorModePassThrough:
-						return "Cursor mode: PassThrough", nil
-					case CursorModeResize:
-						return "Cursor mode: Resize", nil
-					default:
-						return fmt.Sprintf("Cursor mode: Unknown: %+v", server.cursorMode), nil
-					}
+			default:
+			}
+		case "windows":
+			return server.describeWindows()
+		case "workspaces":
+			return server.describeWorkspaces()
+		case "topLevelList":
+		case "cursor":
+			switch mod {
+			case "manager":
+				return fmt.Sprintf("Cursor manager (no useful data): %+v", server.cursorMgr)
+			case "mode":
+				switch server.cursorMode {
+				case CursorModeMove:
+					return "Cursor mode: Move"
+				case CursorModePassThrough:
+					return "Cursor mode: PassThrough"
+				case CursorModeResize:
+					return "Cursor mode: Resize"
 				default:
-					return fmt.Sprintf(
-							"Cursor: Location (%f:%f)",
-							server.cursor.X(),
-							server.cursor.Y()),
-						nil
+					return fmt.Sprintf("Cursor mode: Unknown: %+v", server.cursorMode)
 				}
-			case "seat":
-			case "keyboards":
-			case "cursorMode":
-			case "grabLocation":
-			case "grabGeobox":
-			case "edges":
-			case "outputLayout":
 			default:
-				return "Placeholder", nil
+				return fmt.Sprintf(
+					"Cursor: Location (%f:%f)",
+					server.cursor.X(),
+					server.cursor.Y(),
+				)
 			}
-		} else {
-			return "Unknown command", nil
+		case "seat":
+		case "keyboards":
+		case "cursorMode":
+		case "grabLocation":
+		case "grabGeobox":
+		case "edges":
+		case "outputLayout":
+		default:
+			return "Placeholder"
 		}
-		return "Unknown command", nil
-	})
+	} else if res, ok := server.runCommand(input); ok {
+		return res
+	}
+	return "Unknown command"
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"slices"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Move a window into the scratchpad
+// Tiled windows leave the tree, the window stays mapped but its scene node gets disabled until it's shown
+func (server *Server) moveToScratchpad(window *Window) {
+	if slices.Contains(server.scratchpad, window) {
+		server.hideScratchpad(window)
+		return
+	}
+	if window.state != WindowStateNormal {
+		server.setWindowState(window, WindowStateNormal)
+	}
+	if !window.floating {
+		window.marks = window.workspace.tree.MarksOf(window.id)
+		window.workspace.tree.RemoveApp(window.id, true)
+		window.floating = true
+		window.floatingArea = server.windowArea(window)
+		window.topLevel.Base().SceneTree().Node().Reparent(server.windowLayer(window))
+	}
+	server.scratchpad = append(server.scratchpad, window)
+	server.hideScratchpad(window)
+	server.arrangeTree()
+}
+
+// Take a window out of the scratchpad, leaving it as a normal floating window
+func (server *Server) removeFromScratchpad(window *Window) {
+	server.scratchpad = slices.DeleteFunc(server.scratchpad, func(other *Window) bool {
+		return other == window
+	})
+	window.hidden = false
+}
+
+// Hide a scratchpad window and give the focus back to the tiled windows
+func (server *Server) hideScratchpad(window *Window) {
+	window.hidden = true
+	window.topLevel.Base().SceneTree().Node().SetEnabled(false)
+	if server.focusedWindow() == window {
+		if leaf := server.tree.LastFocusedContainer; leaf != nil && !leaf.IsEmpty {
+			server.focusLeaf(leaf)
+		}
+	}
+}
+
+// Show a scratchpad window centered on the output and focus it
+func (server *Server) showScratchpad(window *Window) {
+	size := window.floatingArea.Size
+	if size.X <= 0 || size.Y <= 0 {
+		size = generaldata.Vector2i{X: server.tree.Resolution.X * 2 / 3, Y: server.tree.Resolution.Y * 2 / 3}
+	}
+	window.floatingArea = generaldata.Rect{Size: server.tree.Resolution}.Centered(size)
+	window.hidden = false
+	// Scratchpad windows show up on whatever workspace is shown
+	previous := window.workspace
+	window.workspace = server.workspace
+	window.topLevel.Base().SceneTree().Node().Reparent(server.windowLayer(window))
+	server.removeEmptyWorkspace(previous)
+	server.arrangeTree()
+
+	surface := window.topLevel.Base().Surface()
+	server.focusTopLevel(&window.topLevel, &surface)
+}
+
+// Show the scratchpad, or move on to the next window in it
+// If a scratchpad window is shown, it gets hidden and the next one is shown instead
+// After the last one the scratchpad stays hidden, so repeatedly toggling cycles through all windows and then nothing
+// Returns false if the scratchpad is empty
+func (server *Server) toggleScratchpad() bool {
+	if len(server.scratchpad) == 0 {
+		return false
+	}
+	shown := slices.IndexFunc(server.scratchpad, func(window *Window) bool {
+		return !window.hidden
+	})
+	if shown >= 0 {
+		server.hideScratchpad(server.scratchpad[shown])
+	}
+	if next := shown + 1; next < len(server.scratchpad) {
+		server.showScratchpad(server.scratchpad[next])
+	}
+	return true
+}
@@ -4,8 +4,12 @@ import (
 	"container/list"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/mstarongithub/way2gay/config"
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+	"github.com/mstarongithub/way2gay/tiler"
 	"github.com/sirupsen/logrus"
 	"github.com/swaywm/go-wlroots/wlroots"
 	"github.com/swaywm/go-wlroots/xkb"
@@ -26,9 +30,22 @@ type Server struct {
 	allocator   wlroots.Allocator
 	scene       wlroots.Scene
 	sceneLayout wlroots.SceneOutputLayout
-
-	xdgShell     wlroots.XDGShell
-	topLevelList list.List
+	// Layers of the scene, later ones are shown above earlier ones
+	workspaceLayer wlroots.SceneTree // Parent of the scene trees of all workspaces
+	overlayLayer   wlroots.SceneTree // Decorations shown above all windows
+	// Rect showing where the next window goes if a container is preselected, created on first use
+	preselectionPreview *wlroots.SceneNode
+
+	xdgShell          wlroots.XDGShell
+	topLevelList      list.List
+	workspaces        map[string]*Workspace // All workspaces by name, hidden ones included
+	workspace         *Workspace            // Workspace that is shown
+	previousWorkspace string                // Name of the workspace shown before the current one, to switch back and forth
+	tree              *tiler.Tree           // Tiling layout of the shown workspace
+	config            *config.Config
+	windows           map[tiler.WindowID]*Window
+	lastWindowID      tiler.WindowID // Last ID handed out to a window. IDs are never reused
+	scratchpad        []*Window      // Windows moved out of the way, shown one at a time in this order
 
 	cursor    wlroots.Cursor
 	cursorMgr wlroots.XCursorManager
@@ -40,10 +57,21 @@ type Server struct {
 	grabX, grabY    float64
 	grabGeobox      wlroots.GeoBox
 	resizeEdges     wlroots.Edges
+	grabButton      uint32                   // Button that made the compositor itself grab the cursor. 0 if a client asked for the grab
+	modifiers       wlroots.KeyboardModifier // Modifiers currently held on the last active keyboard
+	resizeBinding   PointerBinding           // Dragging a tiled window with it moves the borders of the window
+	keyBindings     []KeyBinding             // Commands bound to keys in the config
 
 	outputLayout wlroots.OutputLayout
 
 	outputs []*wlroots.Output
+
+	// Functions other goroutines asked to run on the event loop, see runOnEventLoop
+	queueLock   sync.Mutex
+	queued      []func()
+	loopStopped bool
+	loopDone    chan struct{} // Closed once the event loop stopped
+	wakeupFd    int
 }
 
 type Keyboard struct {
@@ -84,6 +112,11 @@ func (server *Server) focusTopLevel(topLevel *wlroots.XDGTopLevel, surface *wlro
 	if topLevel == nil {
 		return
 	}
+	// Windows on hidden workspaces only become the last focused one there, the keyboard stays on the shown workspace
+	if window := server.findWindow(*topLevel); window != nil && window.workspace != server.workspace {
+		window.workspace.tree.FocusApp(window.id)
+		return
+	}
 	prevSurface := server.seat.KeyboardState().FocusedSurface()
 	logrus.WithFields(logrus.Fields{
 		"previous surface": prevSurface,
@@ -116,6 +149,9 @@ func (server *Server) focusTopLevel(topLevel *wlroots.XDGTopLevel, surface *wlro
 	logrus.WithField("server.topLevelList.Len", server.topLevelList.Len()).Debugln("focusTopLevel")
 	/* Activate the new surface */
 	topLevel.SetActivated(true)
+	if window := server.findWindow(*topLevel); window != nil {
+		window.workspace.tree.FocusApp(window.id)
+	}
 	/*
 	 * Tell the seat to have the keyboard enter this surface. wlroots will keep
 	 * track of this and automatically send key events to the appropriate
@@ -147,6 +183,10 @@ func (server *Server) handleKey(keyboard wlroots.Keyboard, time uint32, keyCode
 			handled = server.handleKeyBinding(sym)
 		}
 	}
+	if !handled && state == wlroots.KeyStatePressed {
+		unmodified := unmodifiedKeySym(keyboard.XKBState(), xkb.KeyCode(keyCode+8))
+		handled = server.runKeyBinding(modifiers, syms, unmodified)
+	}
 
 	if !handled {
 		/* Otherwise, we pass it along to the client. */
@@ -171,6 +211,7 @@ func (server *Server) handleNewKeyboard(dev wlroots.InputDevice) {
 	keyboard.OnModifiers(func(keyboard wlroots.Keyboard) {
 		/* This event is raised when a modifier key, such as shift or alt, is
 		* pressed. We simply communicate this to the client. */
+		server.modifiers = keyboard.Modifiers()
 		server.seat.SetKeyboard(dev)
 		server.seat.NotifyKeyboardModifiers(keyboard)
 	})
@@ -261,6 +302,7 @@ func (server *Server) handleOutputRequestState(output wlroots.Output, state wlro
 		"state":  state,
 	}).Debugln("New state request for output")
 	output.CommitState(state)
+	server.updateTreeResolution()
 }
 
 func (server *Server) handleOuptuDestroy(output wlroots.Output) {
@@ -297,6 +339,7 @@ func (server *Server) handleNewOutput(output wlroots.Output) {
 	/* Atomically applies the new output state. */
 	output.CommitState(oState)
 	oState.Finish()
+	server.updateTreeResolution()
 
 	/* Sets up a listener for the frame event. */
 	output.OnFrame(server.handleNewFrame)
@@ -391,10 +434,21 @@ func (server *Server) processCursorMotion(time uint32) {
 
 func (server *Server) processCursorMove(_ uint32) {
 	/* Move the grabbed toplevel to the new position. */
-	server.grabbedTopLevel.Base().SceneTree().Node().SetPosition(server.cursor.X()-server.grabX, server.cursor.Y()-server.grabY)
+	x, y := server.cursor.X()-server.grabX, server.cursor.Y()-server.grabY
+	server.grabbedTopLevel.Base().SceneTree().Node().SetPosition(x, y)
+	/* Only floating windows get moved, they remember where they were moved to. */
+	if window := server.findWindow(*server.grabbedTopLevel); window != nil && window.floating {
+		window.floatingArea.Position = server.outputRelative(x, y)
+	}
 }
 
 func (server *Server) processCursorResize(_ uint32) {
+	/* Tiled toplevels can't be resized freely, move the splits around them instead. */
+	if window := server.findWindow(*server.grabbedTopLevel); window != nil && server.tree.FindApp(window.id) != nil {
+		server.resizeTiled(window, server.resizeEdges)
+		return
+	}
+
 	/*
 	 * Resizing the grabbed toplevel can be a little bit complicated, because we
 	 * could be resizing from any corner or edge. This not only resizes the
@@ -441,7 +495,16 @@ func (server *Server) processCursorResize(_ uint32) {
 
 	nWidth := nRight - nLeft
 	nHeight := nBottom - nTop
+	box := server.grabbedTopLevel.Base().Geometry()
+	x, y := float64(nLeft-box.X), float64(nTop-box.Y)
+	server.grabbedTopLevel.Base().SceneTree().Node().SetPosition(x, y)
 	server.grabbedTopLevel.Base().TopLevelSetSize(uint32(nWidth), uint32(nHeight))
+	if window := server.findWindow(*server.grabbedTopLevel); window != nil && window.floating {
+		window.floatingArea = generaldata.Rect{
+			Position: server.outputRelative(x, y),
+			Size:     generaldata.Vector2i{X: nWidth, Y: nHeight},
+		}
+	}
 }
 
 func (server *Server) handleSetCursorRequest(client wlroots.SeatClient, surface wlroots.Surface, _ uint32, hotspotX int32, hotspotY int32) {
@@ -464,12 +527,37 @@ func (server *Server) resetCursorMode() {
 	/* Reset the cursor mode to passthrough. */
 	server.cursorMode = CursorModePassThrough
 	server.grabbedTopLevel = nil
+	server.grabButton = 0
 }
 
 func (server *Server) handleCursorButton(_ wlroots.InputDevice, time uint32, button uint32, state wlroots.ButtonState) {
 	/* This event is forwarded by the cursor when a pointer emits a button
 	 * event. */
 
+	/* Dragging the border between two tiled windows, or a tiled window with the
+	 * resize binding, moves the splits around it. The client never sees that
+	 * button. */
+	if state == wlroots.ButtonStatePressed && server.cursorMode == CursorModePassThrough {
+		if button == BTN_LEFT {
+			if window, edges := server.tiledBorderAt(server.cursor.X(), server.cursor.Y()); window != nil {
+				server.beginTiledResize(window, edges, button)
+				return
+			}
+		}
+		if server.resizeBinding.matches(server.modifiers, button) {
+			if topLevel, _, _, _ := server.topLevelAt(server.cursor.X(), server.cursor.Y()); topLevel != nil {
+				if window := server.findWindow(*topLevel); window != nil && server.tree.FindApp(window.id) != nil {
+					server.beginTiledResize(window, server.closestEdges(window), button)
+					return
+				}
+			}
+		}
+	}
+	if state == wlroots.ButtonStateReleased && server.grabButton != 0 && button == server.grabButton {
+		server.resetCursorMode()
+		return
+	}
+
 	/* Notify the client with pointer focus that a button press has occurred */
 	server.seat.NotifyPointerButton(time, button, state)
 
@@ -524,6 +612,10 @@ func (server *Server) handleKeyBinding(sym xkb.KeySym) bool {
 		// focus the next view
 		nextView := server.topLevelList.Front().Next().Value.(*wlroots.XDGTopLevel)
 		nextSurface := nextView.Base().Surface()
+		// Cycling goes through the windows of all workspaces, switch to the one the next window is on
+		if window := server.findWindow(*nextView); window != nil {
+			server.showWorkspace(window.workspace)
+		}
 		server.focusTopLevel(nextView, &nextSurface)
 	default:
 		return false
@@ -541,6 +633,29 @@ func (server *Server) handleMapXDGToplevel(xdgSurface wlroots.XDGSurface) {
 		"server.topLevelList.Len": server.topLevelList.Len(),
 	}).Debugln("handleMapXDGToplevel")
 	server.topLevelList.PushFront(&topLevel)
+	if window := server.findWindow(topLevel); window != nil {
+		server.updateSizeHints(window)
+		switch {
+		case window.floating:
+			// Was floating before getting unmapped, so it stays out of the tree
+		case isDialog(topLevel):
+			server.floatDialog(window)
+		case server.swallowTerminal(window):
+			// Took over the leaf of the terminal it was started from
+		default:
+			// Prefer placeholders from a loaded layout over splitting the focused container
+			window.workspace.tree.Update(func(tx *tiler.Transaction) {
+				if !tx.FillPlaceholder(window.id, topLevel.AppId(), topLevel.Title()) {
+					tx.AddApp(window.id, topLevel.AppId())
+				}
+			})
+		}
+		// Clients can ask to be fullscreen or maximized before they are mapped
+		if state := requestedWindowState(topLevel); state != window.state {
+			server.setWindowState(window, state)
+		}
+	}
+	server.arrangeTree()
 	logrus.WithField("server.topLevelList.Len", server.topLevelList.Len()).Debugln("handleMapXDGToplevel")
 	server.focusTopLevel(&topLevel, &surface)
 	logrus.WithField("server.topLevelList.Len", server.topLevelList.Len()).Debugln("handleMapXDGToplevel")
@@ -555,6 +670,13 @@ func (server *Server) handleUnMapXDGToplevel(xdgSurface wlroots.XDGSurface) {
 		server.resetCursorMode()
 	}
 	server.removeTopLevel(&topLevel)
+	if window := server.findWindow(topLevel); window != nil {
+		window.workspace.tree.RemoveApp(window.id, true)
+		if window.state != WindowStateNormal {
+			server.setWindowState(window, WindowStateNormal)
+		}
+	}
+	server.arrangeTree()
 }
 func (server *Server) handleNewXDGSurface(xdgSurface wlroots.XDGSurface) {
 	/* This event is raised when wlr_xdg_shell receives a new xdg xdgSurface from a
@@ -578,18 +700,27 @@ func (server *Server) handleNewXDGSurface(xdgSurface wlroots.XDGSurface) {
 		}).Fatalln("xdgSurface role is not XDGSurfaceRoleTopLevel")
 	}
 
-	xdgSurface.SetData(server.scene.Tree().NewXDGSurface(xdgSurface.TopLevel().Base()))
+	toplevel := xdgSurface.TopLevel()
+	window := server.newWindow(toplevel)
+	xdgSurface.SetData(server.windowLayer(window).NewXDGSurface(toplevel.Base()))
 	xdgSurface.OnMap(server.handleMapXDGToplevel)
 	xdgSurface.OnUnmap(server.handleUnMapXDGToplevel)
-	xdgSurface.OnDestroy(func(surface wlroots.XDGSurface) {})
 
-	toplevel := xdgSurface.TopLevel()
+	xdgSurface.OnDestroy(func(surface wlroots.XDGSurface) {
+		server.destroyWindow(window)
+	})
 	toplevel.OnRequestMove(func(client wlroots.SeatClient, serial uint32) {
 		server.beginInteractive(&toplevel, CursorModeMove, 0)
 	})
 	toplevel.OnRequestResize(func(client wlroots.SeatClient, serial uint32, edges wlroots.Edges) {
 		server.beginInteractive(&toplevel, CursorModeResize, edges)
 	})
+	onTopLevelStateRequest(toplevel, func(state WindowState, requested bool) {
+		server.handleStateRequest(window, state, requested)
+	})
+	onTopLevelSizeLimits(toplevel, func() {
+		server.updateSizeHints(window)
+	})
 }
 
 func (server *Server) beginInteractive(topLevel *wlroots.XDGTopLevel, mode CursorMode, edges wlroots.Edges) {
@@ -600,6 +731,15 @@ func (server *Server) beginInteractive(topLevel *wlroots.XDGTopLevel, mode Curso
 		/* Deny move/resize requests from unfocused clients. */
 		return
 	}
+	window := server.findWindow(*topLevel)
+	if window != nil && server.tree.FindApp(window.id) != nil {
+		if mode == CursorModeMove {
+			/* Tiled toplevels stay where the tree puts them, only floating ones can be moved. */
+			return
+		}
+		/* Resizing a tiled toplevel moves the splits around it, let the whole drag be undone at once. */
+		server.tree.Checkpoint()
+	}
 	server.grabbedTopLevel = topLevel
 	server.cursorMode = mode
 
@@ -638,13 +778,25 @@ func (server *Server) GetOutputs() []*wlroots.Output {
 	return server.outputs
 }
 
-func NewServer() (server *Server, err error) {
+func NewServer(conf *config.Config) (server *Server, err error) {
 	server = new(Server)
+	server.config = conf
+	server.resizeBinding = resizeBindingFromConfig(conf.Pointer)
+	server.keyBindings = keyBindingsFromConfig(conf.Commands)
 
 	/* The Wayland display is managed by libwayland. It handles accepting
 	 * clients from the Unix socket, manging Wayland globals, and so on. */
 	server.display = wlroots.NewDisplay()
 
+	/* Other goroutines, like the repl, hand work to the event loop through
+	 * runOnEventLoop. Waking the loop up only when there is something to do
+	 * keeps an idle compositor idle. */
+	server.loopDone = make(chan struct{})
+	server.wakeupFd, err = onEventLoopWakeup(server.display.EventLoop(), server.runQueued)
+	if err != nil {
+		return nil, err
+	}
+
 	/* The backend is a wlroots feature which abstracts the underlying input and
 	 * output hardware. The autocreate option will choose the most suitable
 	 * backend based on the current environment, such as opening an X11 window
@@ -700,12 +852,22 @@ func NewServer() (server *Server, err error) {
 	 */
 	server.scene = wlroots.NewScene()
 	server.sceneLayout = server.scene.AttachOutputLayout(server.outputLayout)
+	server.workspaceLayer = server.scene.Tree().NewSceneTree()
+	server.overlayLayer = server.scene.Tree().NewSceneTree()
+	server.workspaces = map[string]*Workspace{}
+	server.showWorkspace(server.getWorkspace(FIRST_WORKSPACE))
 
 	/* Set up xdg-shell version 3. The xdg-shell is a Wayland protocol which is
 	 * used for application windows. For more detail on shells, refer to
 	 * https://drewdevault.com/2018/07/29/Wayland-shells.html.
 	 */
 	server.topLevelList.Init()
+	if conf.Tiling.Template != "" {
+		if err := server.appendLayoutFile(conf.Tiling.Template); err != nil {
+			logrus.WithError(err).Warnln("Failed to load layout template from config")
+		}
+	}
+	server.windows = map[tiler.WindowID]*Window{}
 	server.xdgShell = server.display.XDGShellCreate(3)
 	server.xdgShell.OnNewSurface(server.handleNewXDGSurface)
 
@@ -791,6 +953,15 @@ func (server *Server) Run() error {
 	 * frame events at the refresh rate, and so on. */
 	server.display.Run()
 
+	/* Nothing queued from now on gets to run, so drop it and let the goroutines
+	 * waiting for it know. */
+	server.queueLock.Lock()
+	server.loopStopped = true
+	server.queued = nil
+	closeEventLoopWakeup(server.wakeupFd)
+	server.queueLock.Unlock()
+	close(server.loopDone)
+
 	/* Once s.display.Run() returns, we destroy all clients then shut down the
 	 * server. */
 	server.display.DestroyClients()
@@ -801,6 +972,57 @@ func (server *Server) Run() error {
 	return nil
 }
 
+// Stop the event loop, safe to call from any goroutine
+// Doesn't wait for the loop to stop and does nothing if it already stopped
 func (server *Server) Stop() {
-	server.display.Terminate()
+	server.runOnEventLoop(server.display.Terminate)
+}
+
+// Run a function on the event loop, safe to call from any goroutine
+// Functions queued before Run wait for the loop to start
+// Returns false without queueing the function if the event loop already stopped
+func (server *Server) runOnEventLoop(fn func()) bool {
+	server.queueLock.Lock()
+	defer server.queueLock.Unlock()
+
+	if server.loopStopped {
+		return false
+	}
+	server.queued = append(server.queued, fn)
+	wakeEventLoop(server.wakeupFd)
+	return true
+}
+
+// Same as runOnEventLoop, but waits for the function to finish
+// Returns false if the event loop stopped before getting to it
+func (server *Server) callOnEventLoop(fn func()) bool {
+	done := make(chan struct{})
+	if !server.runOnEventLoop(func() { fn(); close(done) }) {
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	case <-server.loopDone:
+		// The function might still have run right before the loop stopped
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// Run everything queued through runOnEventLoop
+// Has to be called from the event loop
+func (server *Server) runQueued() {
+	server.queueLock.Lock()
+	queued := server.queued
+	server.queued = nil
+	server.queueLock.Unlock()
+
+	for _, fn := range queued {
+		fn()
+	}
 }
@@ -0,0 +1,35 @@
+package main
+
+// Let a floating window follow the user across workspace switches, or leave it on its workspace again
+// Tiled windows belong to the tree of their workspace, so only floating windows can be sticky
+// Returns false if the window should become sticky but isn't floating
+func (server *Server) setSticky(window *Window, sticky bool) bool {
+	if sticky && !window.floating {
+		return false
+	}
+	window.sticky = sticky
+	return true
+}
+
+// Toggle whether the focused window is sticky
+// Returns the focused window, nil if there is none, and whether it could be changed
+func (server *Server) toggleSticky() (*Window, bool) {
+	window := server.focusedWindow()
+	if window == nil {
+		return nil, false
+	}
+	return window, server.setSticky(window, !window.sticky)
+}
+
+// Move all sticky windows to the active workspace, so they stay visible after a switch
+func (server *Server) moveStickyWindows() {
+	for _, window := range server.windows {
+		if !window.sticky {
+			continue
+		}
+		window.workspace = server.workspace
+		node := window.topLevel.Base().SceneTree().Node()
+		node.Reparent(server.windowLayer(window))
+		node.RaiseToTop()
+	}
+}
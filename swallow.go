@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Get the parent process of a process from /proc
+// Returns 0 if the process doesn't exist (anymore)
+func parentPid(pid int) int {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0
+	}
+	// The command name in the second field can contain spaces, so start after its closing parenthesis
+	// The state comes next, then the parent pid
+	_, rest, found := strings.Cut(string(stat), ") ")
+	if !found {
+		return 0
+	}
+	fields := strings.Fields(rest)
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0
+	}
+	return ppid
+}
+
+// Find the terminal window on the same workspace a window's process was started from, by walking up its parent processes
+// Returns nil if there is none or swallowing isn't allowed for the window
+func (server *Server) findTerminal(window *Window) *Window {
+	conf := server.config.Tiling.Swallow
+	if !conf.Enabled || window.pid == 0 || slices.Contains(conf.Deny, window.topLevel.AppId()) {
+		return nil
+	}
+	terminals := map[int]*Window{}
+	for _, other := range server.windows {
+		if other != window && other.pid != 0 && other.workspace == window.workspace && slices.Contains(conf.Terminals, other.topLevel.AppId()) {
+			terminals[other.pid] = other
+		}
+	}
+	if len(terminals) == 0 {
+		return nil
+	}
+	// pid 1 is init, nothing above it can be a terminal
+	for pid := parentPid(window.pid); pid > 1; pid = parentPid(pid) {
+		if terminal, ok := terminals[pid]; ok {
+			return terminal
+		}
+	}
+	return nil
+}
+
+// Let a newly mapped window take over the leaf of the terminal it was started from and hide that terminal
+// The terminal comes back once the window is removed from the tree again
+// Returns false if the window doesn't belong to a tiled terminal
+func (server *Server) swallowTerminal(window *Window) bool {
+	terminal := server.findTerminal(window)
+	if terminal == nil || terminal.state != WindowStateNormal || terminal.floating {
+		return false
+	}
+	if !window.workspace.tree.Swallow(terminal.id, window.id, window.topLevel.AppId()) {
+		return false
+	}
+	// The tree doesn't arrange the terminal anymore, it gets enabled again when it is put back
+	terminal.topLevel.Base().SceneTree().Node().SetEnabled(false)
+	logrus.WithFields(logrus.Fields{
+		"window":   window.id,
+		"terminal": terminal.id,
+	}).Debugln("Window swallowed terminal")
+	return true
+}
@@ -3,8 +3,9 @@ package tiler
 import (
 	"errors"
 	"fmt"
-	"math"
+	"regexp"
 	"sync"
+	"sync/atomic"
 
 	generaldata "github.com/mstarongithub/way2gay/general-data"
 )
@@ -12,27 +13,50 @@ import (
 type NodeType int
 type Direction int
 
+// Compositor assigned handle of a single window
+// Stays the same for the whole lifetime of the window, unlike its app ID
+type WindowID uint64
+
 const (
 	NodeTypeLeaf = NodeType(iota)
 	NodeTypeBranch
 )
 
-const EMPTY_LEAF_ID = 0
+// Window ID of empty leaves. The compositor must never hand it out
+const EMPTY_WINDOW_ID = WindowID(0)
 
 const (
 	DirectionVertical = Direction(iota)
 	DirectionHorizontal
 )
 
+// Range of AspectLeft, so neither child of a branch ends up without space
+const (
+	MIN_ASPECT = 1
+	MAX_ASPECT = 99
+)
+
 type (
 	// A tiling tree. One tree per screen/workspace
 	// Children resolution calculated down the tree
 	Tree struct {
 		Resolution           generaldata.Vector2i // Final space the tree is occupying
-		nameToId             map[string]int       // Stores all leaflet IDs for quick lookup
+		leaves               map[WindowID]*Leaf   // Index of all non-empty leaves for quick lookup
 		Root                 Node
 		LastFocusedContainer *Leaf
 		LastFocusedParent    *Branch
+		layout               Layout // Automatic layout placing the windows. Nil if the structure of the tree is used
+		gaps                 Gaps
+		splitToRight         bool                   // Put split containers on the right/bottom and new windows on the left/top
+		subscribers          map[int]Subscriber     // Everyone listening for changes, by subscription
+		lastSubscriber       int                    // Last handed out subscription
+		pending              []Event                // Changes made by the current operation
+		before               []LeafGeometry         // Geometry from before the current operation. Only kept while there are subscribers
+		hints                map[WindowID]SizeHints // Size limits of windows, see SetSizeHints
+		undo                 []snapshot             // Structures before the last changes, the latest one last
+		redo                 []snapshot             // Structures that were undone, the latest one last
+		transaction          *Transaction           // Transaction currently applied to the tree. Nil outside of transactions
+		updating             atomic.Bool            // Whether Update is running, to catch it being called from within itself
 		lock                 sync.Mutex
 	}
 
@@ -48,16 +72,22 @@ type (
 		ChildLeft  Node      // Is the top child if split vertically
 		ChildRight Node      // Is the bottom child if split vertically
 		AspectLeft int       // Percentage the left child has of the container space
+		Mode       ContainerMode
 
-		// Range of IDs the leaflets have. Includes sub-branches
-		idRangeStart int // Start is inclusive
-		idRangeEnd   int // End is exclusive
+		parent    *Branch // Branch containing this one. Nil if this is the root
+		showRight bool    // Whether the right child is the visible tab. Only used if not split
 	}
 
 	Leaf struct {
-		leafID  int    // Unique ID for this leaf. ONLY CHANGE WHEN INSERTING NEW LEAFS AND ON CHANGE ALSO UPDATE THE MAPPING IN THE TREE ROOT
-		AppId   string // TODO: Should be a reference to the app contained
-		IsEmpty bool   // Indicates that this leaf is empty
+		Window  WindowID       // Window contained in this leaf
+		AppId   string         // App ID of the contained window. Only metadata, multiple leaves can share the same app ID
+		IsEmpty bool           // Indicates that this leaf is empty
+		Marks   []string       // Names the user gave the contained window. Every mark is only used once per tree
+		Title   *regexp.Regexp // Pattern the title of a window has to match to fill this leaf. Only used by placeholders
+
+		parent       *Branch       // Branch containing this leaf. Nil if this is the root
+		swallowed    *Leaf         // Previous content of this leaf, hidden while the current window is around. Its own parent is unused
+		preselection *Preselection // Where the next window added at this leaf goes. Nil if not preselected
 	}
 
 	LeafNeighbours struct {
@@ -70,7 +100,7 @@ type (
 
 func NewTree(resolution generaldata.Vector2i) Tree {
 	baseLeaf := Leaf{
-		leafID:  EMPTY_LEAF_ID,
+		Window:  EMPTY_WINDOW_ID,
 		AppId:   "",
 		IsEmpty: true,
 	}
@@ -80,319 +110,334 @@ func NewTree(resolution generaldata.Vector2i) Tree {
 		Leaf:   &baseLeaf,
 	}
 	return Tree{
-		Resolution: resolution,
-		nameToId: map[string]int{
-			"": EMPTY_LEAF_ID, // Empty appID will always map to the empty leaf. Please don't overwrite
-		},
+		Resolution:           resolution,
+		leaves:               map[WindowID]*Leaf{},
 		Root:                 baseNode,
 		LastFocusedContainer: &baseLeaf,
 		LastFocusedParent:    nil,
 	}
 }
 
-// Find the leaflet containing the given app
-func (t *Tree) FindApp(appId string) *Leaf {
+// Find the leaflet containing the given window
+func (t *Tree) FindApp(window WindowID) *Leaf {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	uID, ok := t.nameToId[appId]
-	if !ok {
-		// Case app not in tree
-		return nil
-	}
-	switch t.Root.Type {
-	case NodeTypeLeaf:
-		// Only one leaf stored
-		if t.Root.Leaf.leafID == uID {
-			return t.Root.Leaf
-		}
-	case NodeTypeBranch:
-		return t.Root.Branch.findUID(uID)
-	}
+	return t.findApp(window)
+}
 
-	return nil
+// Same as FindApp, but expects the caller to already hold the lock
+func (t *Tree) findApp(window WindowID) *Leaf {
+	return t.leaves[window]
 }
 
-func (t *Tree) findAndTrace(appId string) (*Leaf, []Node) {
+// Check whether there are neither windows nor placeholders waiting for windows in the tree
+func (t *Tree) IsEmpty() bool {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	uID, ok := t.nameToId[appId]
-	if !ok {
-		// Case app not in tree
-		return nil, []Node{}
+	if len(t.leaves) > 0 {
+		return false
 	}
-	switch t.Root.Type {
-	case NodeTypeLeaf:
-		// Only one leaf stored
-		if t.Root.Leaf.leafID == uID {
-			return t.Root.Leaf, []Node{t.Root}
+	empty := true
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		if leaf.IsPlaceholder() {
+			empty = false
 		}
-	case NodeTypeBranch:
-		return t.Root.Branch.findAndTrace(uID)
-	}
-
-	return nil, []Node{}
+	})
+	return empty
 }
 
-// Swap two apps
-func (t *Tree) SwapApp(app1, app2 string) {
+// Find all non-empty leaflets with the given app ID
+// Returned in left to right order
+func (t *Tree) FindAppsById(appId string) []*Leaf {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	leaflet1 := t.FindApp(app1)
-	leaflet2 := t.FindApp(app2)
+	leaves := []*Leaf{}
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		if !leaf.IsEmpty && leaf.AppId == appId {
+			leaves = append(leaves, leaf)
+		}
+	})
+	return leaves
+}
+
+// Swap two windows
+func (t *Tree) SwapApp(window1, window2 WindowID) {
+	t.beginChange()
+	defer t.endChange()
+
+	t.swapApp(window1, window2)
+}
+
+// Same as SwapApp, but expects the caller to already hold the lock
+func (t *Tree) swapApp(window1, window2 WindowID) {
+	leaflet1 := t.findApp(window1)
+	leaflet2 := t.findApp(window2)
 
 	if leaflet1 == nil || leaflet2 == nil {
 		return
 	}
-
-	// Don't swap leaf IDs. That would cause problems with search
-	// Store data of leaflet1
-	tempName := leaflet1.AppId
-	tempID := leaflet1.leafID
-	// Then swap IDs in the name to ID map
-	t.nameToId[app1] = leaflet2.leafID
-	t.nameToId[app2] = tempID
-	// Then the names
-	leaflet1.AppId = leaflet2.AppId
-	leaflet2.AppId = tempName
+	t.remember()
+	t.swapContents(leaflet1, leaflet2)
 }
 
-// Add a new app to the tree
+// Add a new window to the tree
 // Will split the last focused container if needed
-func (t *Tree) AddApp(appId string) {
-	t.lock.Lock()
-	defer t.lock.Unlock()
-	t.SplitLastFocusedContainer()
-	newLeaf := Leaf{
-		leafID:  t.LastFocusedParent.idRangeEnd - 1,
-		AppId:   appId,
-		IsEmpty: false,
-	}
-	t.nameToId[appId] = newLeaf.leafID
-	t.LastFocusedParent.ChildRight = Node{
-		Type: NodeTypeLeaf,
-		Leaf: &newLeaf,
+// An empty focused leaf, like the root of a new tree, is taken over instead
+// Placeholders waiting for other apps get split too instead of being taken over
+func (t *Tree) AddApp(window WindowID, appId string) {
+	t.beginChange()
+	defer t.endChange()
+
+	t.addApp(window, appId)
+}
+
+// Same as AddApp, but expects the caller to already hold the lock
+func (t *Tree) addApp(window WindowID, appId string) {
+	newLeaf := t.LastFocusedContainer
+	if !newLeaf.IsEmpty || newLeaf.IsPlaceholder() {
+		newLeaf = t.SplitLastFocusedContainer()
 	}
-	t.LastFocusedContainer = &newLeaf
+	// An empty leaf is taken over as it is, there is nothing to split for a preselection
+	newLeaf.preselection = nil
+	t.fillLeaf(newLeaf, window, appId)
 }
 
-// Remove an app from the tree
+// Put a window into an empty leaf and focus it
+func (t *Tree) fillLeaf(leaf *Leaf, window WindowID, appId string) {
+	leaf.Window = window
+	leaf.AppId = appId
+	leaf.IsEmpty = false
+	leaf.Title = nil
+	t.leaves[window] = leaf
+	t.emit(Event{Type: EventWindowAdded, Window: window})
+	t.focusLeaf(leaf)
+}
+
+// Remove a window from the tree
 // If popParent is true, the parent container will be removed and replaced with the other child
-func (t *Tree) RemoveApp(appId string, popParent bool) {
-	t.lock.Lock()
-	defer t.lock.Unlock()
+// A window that swallowed another one gives its leaf back to that one instead
+func (t *Tree) RemoveApp(window WindowID, popParent bool) {
+	t.beginChange()
+	defer t.endChange()
+
+	t.removeApp(window, popParent)
+}
 
-	leaf, trace := t.findAndTrace(appId)
+// Same as RemoveApp, but expects the caller to already hold the lock
+func (t *Tree) removeApp(window WindowID, popParent bool) {
+	leaf := t.findApp(window)
 	if leaf == nil {
-		// Didn't find app, nothing to do
+		// Not in the tree, but it might be hidden by a window that swallowed it
+		t.forgetSwallowed(window)
+		return
+	}
+	t.remember()
+	// 1. Remove window from the index
+	delete(t.leaves, window)
+	t.emit(Event{Type: EventWindowRemoved, Window: window})
+	if leaf.swallowed != nil {
+		t.unswallow(leaf)
 		return
 	}
-	// 1. Remove app from name to ID map
-	delete(t.nameToId, appId)
 
 	// 2. Set app leaflet to empty
 	leaf.IsEmpty = true
+	leaf.Window = EMPTY_WINDOW_ID
 	leaf.AppId = ""
-	leaf.leafID = EMPTY_LEAF_ID
+	leaf.Marks = nil
 	// 3. If told to pop parent, remove parent branch and replace with other child
 	// But only do so if not top level
-	if popParent && len(trace) > 1 {
-		parent := trace[1]
-
-		// Check if parent is top level
-		if parent.Branch == t.Root.Branch {
-			// Case: Parent is top level
-			if parent.Branch.ChildLeft.Leaf.leafID == leaf.leafID {
-				t.Root = parent.Branch.ChildRight
-			} else {
-				t.Root = parent.Branch.ChildLeft
-			}
-			// And GC should clean up the old branch and leaf to delete
-		} else {
-			// Technically there is an edge case here where the other child is empty node
-			// Doesn't matter though since empty node is empty node
-			parentsParent := trace[2]
-			if parentsParent.Branch.ChildLeft.Branch == parent.Branch {
-				// Parent is left child
-				if parent.Branch.ChildLeft.Leaf.leafID == leaf.leafID {
-					parentsParent.Branch.ChildLeft = parent.Branch.ChildRight
-				} else {
-					parentsParent.Branch.ChildLeft = parent.Branch.ChildLeft
-				}
-			} else {
-				// Parent is right child
-				if parent.Branch.ChildLeft.Leaf.leafID == leaf.leafID {
-					parentsParent.Branch.ChildRight = parent.Branch.ChildRight
-				} else {
-					parentsParent.Branch.ChildRight = parent.Branch.ChildLeft
-				}
-			}
+	if popParent && leaf.parent != nil {
+		parent := leaf.parent
+		sibling := parent.otherChild(leaf)
+		t.replaceChild(parent.parent, Node{Type: NodeTypeBranch, Branch: parent}, sibling)
+
+		// Focus can't stay on a leaf or branch that isn't part of the tree anymore
+		if t.LastFocusedContainer == leaf || t.LastFocusedParent == parent {
+			t.focusLeaf(sibling.firstLeaf())
 		}
-		// And again, GC should clean up the old branch and leaf to delete
+		// GC will clean up the old branch and leaf
 	}
 }
 
-// Split the last focused container into a new branch
-// the container itself will be placed as the left child of the new branch
-// Right side will be an empty leaf
-// Updates leaf IDs if needed
-func (t *Tree) SplitLastFocusedContainer() {
-	// Case: No last focused parent -> Working at top level
-	if t.LastFocusedParent == nil {
-		// First make a new split container
-		newSplit := Branch{
-			// Default to vertical for initial split
-			// TODO: Make this configurable
-			Direction: DirectionVertical,
-			// First split container will always be max range
-			idRangeStart: math.MinInt,
-			idRangeEnd:   math.MaxInt,
-			// Left entry is last focused leaf
-			ChildLeft: t.Root,
-			// Right entry is empty
-			ChildRight: Node{
-				Type: NodeTypeLeaf,
-				Leaf: &Leaf{
-					leafID:  EMPTY_LEAF_ID,
-					IsEmpty: true,
-				},
-			},
-		}
-		// Then update root
-		t.Root = Node{
-			Type:   NodeTypeBranch,
-			Branch: &newSplit,
-		}
-		t.LastFocusedParent = &newSplit
-		t.LastFocusedContainer = t.Root.Branch.ChildLeft.Leaf
+// Split the last focused container into a new branch holding the container and a new empty leaf
+// If the container is preselected, the preselection decides where the empty leaf goes and how much space it gets
+// and is used up. Otherwise the direction alternates with every level and both get half of the space,
+// with the container placed as the left child unless the tree is set to split to the right
+// Inside tabbed and stacked containers the new branch becomes part of the container, so the empty leaf is another tab
+// The focus stays on the container, with the new branch as its parent
+// Returns the new empty leaf
+func (t *Tree) SplitLastFocusedContainer() *Leaf {
+	focused := t.LastFocusedContainer
+	// Default to vertical for the initial split, then alternate
+	newDirection := DirectionVertical
+	if focused.parent != nil && focused.parent.Direction == DirectionVertical {
+		newDirection = DirectionHorizontal
+	}
+	newBranch := Branch{
+		Direction:  newDirection,
+		AspectLeft: 50,
+	}
+	if focused.parent != nil && focused.parent.Mode != ContainerModeSplit {
+		newBranch.Direction = focused.parent.Direction
+		newBranch.Mode = focused.parent.Mode
+	}
+	containerFirst := !t.splitToRight
+	if focused.preselection != nil {
+		newBranch, containerFirst = focused.preselection.branch()
+		focused.preselection = nil
+	}
+
+	// Replace the container with the new branch, then move the container into the branch
+	packagedLeaf := Node{
+		Type: NodeTypeLeaf,
+		Leaf: focused,
+	}
+	emptyLeaf := Node{
+		Type: NodeTypeLeaf,
+		Leaf: &Leaf{
+			Window:  EMPTY_WINDOW_ID,
+			IsEmpty: true,
+		},
+	}
+	t.replaceChild(focused.parent, packagedLeaf, Node{Type: NodeTypeBranch, Branch: &newBranch})
+	if containerFirst {
+		newBranch.setChildren(packagedLeaf, emptyLeaf)
 	} else {
-		// Todo: Package last focused node into container, replace it with new split, then set left container of new split to last focused and update root
-		// 1. Package last focused node into container
-		packagedLeaf := Node{
-			Type: NodeTypeLeaf,
-			Leaf: t.LastFocusedContainer,
-		}
-		// 2. Create new branch
-		newDirection := DirectionVertical
-		if t.LastFocusedParent.Direction == DirectionVertical {
-			newDirection = DirectionHorizontal
-		}
-		// New range start is same as parent range start if left side, else it's the middle
-		newRangeStart := t.LastFocusedParent.idRangeStart
-		if t.LastFocusedContainer.leafID == t.LastFocusedParent.ChildLeft.Leaf.leafID {
-			// Left side: Copy from parent
-			newRangeStart = t.LastFocusedParent.idRangeStart
-		} else {
-			// Right side: Start is middle
-			newRangeStart = t.LastFocusedParent.idRangeStart + ((t.LastFocusedParent.idRangeEnd - t.LastFocusedParent.idRangeStart) / 2)
-		}
-		// Same here, except middle if left, else end
-		newRangeEnd := t.LastFocusedParent.idRangeEnd
-		if t.LastFocusedContainer.leafID == t.LastFocusedParent.ChildRight.Leaf.leafID {
-			// Right side: Copy from parent
-			newRangeEnd = t.LastFocusedParent.idRangeEnd
-		} else {
-			// Left side: End is middle
-			newRangeEnd = t.LastFocusedParent.idRangeStart + ((t.LastFocusedParent.idRangeEnd - t.LastFocusedParent.idRangeStart) / 2)
-		}
-
-		newBranch := Branch{
-			Direction: newDirection,
-			ChildLeft: packagedLeaf,
-			ChildRight: Node{
-				Type: NodeTypeLeaf,
-				Leaf: &Leaf{
-					leafID:  EMPTY_LEAF_ID,
-					IsEmpty: true,
-				},
-			},
-			AspectLeft:   50,
-			idRangeStart: newRangeStart,
-			idRangeEnd:   newRangeEnd,
-		}
+		newBranch.setChildren(emptyLeaf, packagedLeaf)
+	}
+	t.LastFocusedParent = &newBranch
+	return emptyLeaf.Leaf
+}
 
-		// 3. Replace self in parent branch with new branch
-		if t.LastFocusedParent.ChildLeft.Leaf.leafID == t.LastFocusedContainer.leafID {
-			t.LastFocusedParent.ChildLeft = Node{
-				Type:   NodeTypeBranch,
-				Branch: &newBranch,
-			}
-		} else {
-			t.LastFocusedParent.ChildRight = Node{
-				Type:   NodeTypeBranch,
-				Branch: &newBranch,
-			}
+// Swap the contents of two leaves, keeping the index up to date
+// Expects the caller to hold the lock
+func (t *Tree) swapContents(leaf1, leaf2 *Leaf) {
+	leaf1.Window, leaf2.Window = leaf2.Window, leaf1.Window
+	leaf1.AppId, leaf2.AppId = leaf2.AppId, leaf1.AppId
+	leaf1.IsEmpty, leaf2.IsEmpty = leaf2.IsEmpty, leaf1.IsEmpty
+	leaf1.Marks, leaf2.Marks = leaf2.Marks, leaf1.Marks
+	leaf1.swallowed, leaf2.swallowed = leaf2.swallowed, leaf1.swallowed
+	for _, leaf := range []*Leaf{leaf1, leaf2} {
+		if !leaf.IsEmpty {
+			t.leaves[leaf.Window] = leaf
+			t.emit(Event{Type: EventWindowMoved, Window: leaf.Window})
 		}
+	}
+}
 
-		// 4. Update root
-		t.LastFocusedParent = &newBranch
-
-		// 5. Update ID if needed
-		// Edgecase of parent range being one element should be fixed
-		if t.LastFocusedContainer.leafID > t.LastFocusedParent.idRangeEnd {
-			t.LastFocusedContainer.leafID = t.LastFocusedParent.idRangeEnd - 1
-		} else if t.LastFocusedContainer.leafID < t.LastFocusedParent.idRangeStart {
-			t.LastFocusedContainer.leafID = t.LastFocusedParent.idRangeStart
+// Copy the window metadata like marks back onto the new leaves of windows that were taken out of the tree and put back in
+// Expects the caller to hold the lock
+func (t *Tree) restoreMetadata(previous []Leaf) {
+	for _, leaf := range previous {
+		if moved := t.leaves[leaf.Window]; moved != nil {
+			moved.Marks = leaf.Marks
+			moved.swallowed = leaf.swallowed
 		}
 	}
 }
 
-// Recursively find the leaflet with the given ID
-func (b *Branch) findUID(uID int) *Leaf {
-	if b.ChildLeft.Type == NodeTypeLeaf && b.ChildLeft.Leaf.leafID == uID {
-		return b.ChildLeft.Leaf
-	}
-	if b.ChildRight.Type == NodeTypeLeaf && b.ChildRight.Leaf.leafID == uID {
-		return b.ChildRight.Leaf
+// Replace a direct child of a branch
+// A nil parent means the root gets replaced
+func (t *Tree) replaceChild(parent *Branch, old Node, replacement Node) {
+	switch {
+	case parent == nil:
+		t.Root = replacement
+		replacement.setParent(nil)
+	case sameNode(parent.ChildLeft, old):
+		parent.setChildren(replacement, parent.ChildRight)
+	case sameNode(parent.ChildRight, old):
+		parent.setChildren(parent.ChildLeft, replacement)
 	}
-	if b.ChildLeft.Type == NodeTypeBranch && uID >= b.ChildLeft.Branch.idRangeStart && uID < b.ChildLeft.Branch.idRangeEnd {
-		return b.ChildLeft.Branch.findUID(uID)
-	}
-	if b.ChildRight.Type == NodeTypeBranch && uID >= b.ChildRight.Branch.idRangeStart && uID < b.ChildRight.Branch.idRangeEnd {
-		return b.ChildRight.Branch.findUID(uID)
+}
+
+// Set both children of a branch and point their parents at it
+func (b *Branch) setChildren(left, right Node) {
+	b.ChildLeft = left
+	b.ChildRight = right
+	left.setParent(b)
+	right.setParent(b)
+}
+
+// Get the child that doesn't contain the given leaf directly
+func (b *Branch) otherChild(leaf *Leaf) Node {
+	if b.ChildLeft.Type == NodeTypeLeaf && b.ChildLeft.Leaf == leaf {
+		return b.ChildRight
 	}
-	return nil
+	return b.ChildLeft
 }
 
-// Same as findUID but also returns the trace of nodes leading to the leaflet
-// The order of the trace is from the leaflet to the root
-// Trace is empty if the leaflet is not found
-func (b *Branch) findAndTrace(uID int) (*Leaf, []Node) {
-	if b.ChildLeft.Type == NodeTypeLeaf && b.ChildLeft.Leaf.leafID == uID {
-		return b.ChildLeft.Leaf, []Node{b.ChildLeft}
+// Restore all parent pointers and the leaf index from the structure of the tree
+// Used after the nodes of a tree have been assembled by hand
+func (t *Tree) relink() {
+	t.leaves = map[WindowID]*Leaf{}
+	t.Root.setParent(nil)
+	t.Root.relink(t.leaves)
+	if t.LastFocusedContainer != nil {
+		t.LastFocusedParent = t.LastFocusedContainer.parent
 	}
-	if b.ChildRight.Type == NodeTypeLeaf && b.ChildRight.Leaf.leafID == uID {
-		return b.ChildRight.Leaf, []Node{b.ChildRight}
+}
+
+func (n *Node) relink(leaves map[WindowID]*Leaf) {
+	switch n.Type {
+	case NodeTypeLeaf:
+		if !n.Leaf.IsEmpty {
+			leaves[n.Leaf.Window] = n.Leaf
+		}
+	case NodeTypeBranch:
+		n.Branch.setChildren(n.Branch.ChildLeft, n.Branch.ChildRight)
+		n.Branch.ChildLeft.relink(leaves)
+		n.Branch.ChildRight.relink(leaves)
 	}
-	if b.ChildLeft.Type == NodeTypeBranch && uID >= b.ChildLeft.Branch.idRangeStart && uID < b.ChildLeft.Branch.idRangeEnd {
-		leaf, trace := b.ChildLeft.Branch.findAndTrace(uID)
-		trace = append(trace, Node{Type: NodeTypeBranch, Branch: b})
-		return leaf, trace
+}
+
+// Update the parent pointer of whatever the node wraps
+func (n Node) setParent(parent *Branch) {
+	switch n.Type {
+	case NodeTypeLeaf:
+		n.Leaf.parent = parent
+	case NodeTypeBranch:
+		n.Branch.parent = parent
 	}
-	if b.ChildRight.Type == NodeTypeBranch && uID >= b.ChildRight.Branch.idRangeStart && uID < b.ChildRight.Branch.idRangeEnd {
-		leaf, trace := b.ChildRight.Branch.findAndTrace(uID)
-		trace = append(trace, Node{Type: NodeTypeBranch, Branch: b})
-		return leaf, trace
+}
+
+// Check if two nodes point to the same leaf or branch
+func sameNode(a, b Node) bool {
+	return a.Type == b.Type && a.Leaf == b.Leaf && a.Branch == b.Branch
+}
+
+// Call f for every leaf below this node, in left to right order
+func (n *Node) walkLeaves(f func(*Leaf)) {
+	switch n.Type {
+	case NodeTypeLeaf:
+		if n.Leaf != nil {
+			f(n.Leaf)
+		}
+	case NodeTypeBranch:
+		if n.Branch != nil {
+			n.Branch.ChildLeft.walkLeaves(f)
+			n.Branch.ChildRight.walkLeaves(f)
+		}
 	}
-	return nil, []Node{}
 }
 
-func checkNode(node *Node, rangeStart, rangeEnd int) error {
+func checkNode(node *Node, parent *Branch) error {
 	if node == nil {
 		return errors.New("node is nil")
 	}
 	if node.Type == NodeTypeBranch {
-		return checkBranch(node)
+		return checkBranch(node, parent)
 	} else if node.Type == NodeTypeLeaf {
-		return checkLeaf(node, rangeStart, rangeEnd)
+		return checkLeaf(node, parent)
 	}
 
 	return errors.New("invalid node type")
 }
 
-func checkBranch(node *Node) error {
+func checkBranch(node *Node, parent *Branch) error {
 	if node == nil {
 		return errors.New("node is nil")
 	}
@@ -402,22 +447,28 @@ func checkBranch(node *Node) error {
 	if node.Branch == nil {
 		return errors.New("stored branch is nil")
 	}
-	if node.Branch.idRangeEnd < node.Branch.idRangeStart {
-		return fmt.Errorf("invalid ID range: %d - %d", node.Branch.idRangeStart, node.Branch.idRangeEnd)
+	if node.Branch.parent != parent {
+		return errors.New("branch doesn't point to its parent")
+	}
+	if node.Branch.AspectLeft < 0 || node.Branch.AspectLeft > 100 {
+		return fmt.Errorf("aspect %d out of range", node.Branch.AspectLeft)
+	}
+	if node.Branch.Mode < ContainerModeSplit || node.Branch.Mode > ContainerModeStacked {
+		return fmt.Errorf("invalid container mode %d", node.Branch.Mode)
 	}
 
-	if err := checkNode(&node.Branch.ChildLeft, node.Branch.idRangeStart, node.Branch.idRangeEnd); err != nil {
+	if err := checkNode(&node.Branch.ChildLeft, node.Branch); err != nil {
 		return fmt.Errorf("Left child: %w", err)
 	}
 
-	if err := checkNode(&node.Branch.ChildRight, node.Branch.idRangeStart, node.Branch.idRangeEnd); err != nil {
-		return fmt.Errorf("Left child: %w", err)
+	if err := checkNode(&node.Branch.ChildRight, node.Branch); err != nil {
+		return fmt.Errorf("Right child: %w", err)
 	}
 
 	return nil
 }
 
-func checkLeaf(node *Node, rangeStart, rangeEnd int) error {
+func checkLeaf(node *Node, parent *Branch) error {
 	if node == nil {
 		return errors.New("node is nil")
 	}
@@ -427,8 +478,17 @@ func checkLeaf(node *Node, rangeStart, rangeEnd int) error {
 	if node.Leaf == nil {
 		return errors.New("leaf is nil")
 	}
-	if node.Leaf.leafID < rangeStart || node.Leaf.leafID >= rangeEnd {
-		return fmt.Errorf("leaf ID %v out of range (%v, %v)", node.Leaf.leafID, rangeStart, rangeEnd)
+	if node.Leaf.parent != parent {
+		return errors.New("leaf doesn't point to its parent")
+	}
+	if node.Leaf.IsEmpty != (node.Leaf.Window == EMPTY_WINDOW_ID) {
+		return fmt.Errorf("leaf with window %d doesn't match its empty flag", node.Leaf.Window)
+	}
+	if node.Leaf.IsEmpty && len(node.Leaf.Marks) > 0 {
+		return errors.New("empty leaf with marks")
+	}
+	if node.Leaf.IsEmpty && node.Leaf.swallowed != nil {
+		return errors.New("empty leaf with a swallowed window")
 	}
 
 	return nil
@@ -1,7 +1,6 @@
 package tiler
 
 import (
-	"math"
 	"testing"
 
 	generaldata "github.com/mstarongithub/way2gay/general-data"
@@ -16,8 +15,8 @@ func TestBTreeCreate(t *testing.T) {
 	if tree.LastFocusedContainer != tree.Root.Leaf {
 		t.Errorf("Last focused container is not the root leaf")
 	}
-	if tree.Root.Leaf.leafID != EMPTY_LEAF_ID {
-		t.Errorf("Root leaf ID is the empty leaf ID")
+	if tree.Root.Leaf.Window != EMPTY_WINDOW_ID {
+		t.Errorf("Root leaf window is not the empty window")
 	}
 	if tree.Root.Leaf.AppId != "" {
 		t.Errorf("Root leaf app ID is not empty")
@@ -25,19 +24,128 @@ func TestBTreeCreate(t *testing.T) {
 	if !tree.Root.Leaf.IsEmpty {
 		t.Errorf("Root leaf is not marked as empty")
 	}
+	if !tree.IsEmpty() {
+		t.Errorf("New tree isn't empty")
+	}
+}
+
+// Placeholders keep a tree from being empty, leftover empty leaves don't
+func TestBTreeIsEmpty(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.AddApp(1, "a")
+	tree.AddApp(2, "b")
+	if tree.IsEmpty() {
+		t.Errorf("Tree with windows is empty")
+	}
+	tree.RemoveApp(1, false)
+	tree.RemoveApp(2, false)
+	if !tree.IsEmpty() {
+		t.Errorf("Tree with only empty leaves isn't empty")
+	}
+	if err := tree.AppendLayout(LayoutNode{AppId: "a"}); err != nil {
+		t.Fatalf("Failed to append layout: %s", err)
+	}
+	if tree.IsEmpty() {
+		t.Errorf("Tree with a placeholder is empty")
+	}
 }
 
 func TestBTreeInsert(t *testing.T) {
 	tree := NewTree(generaldata.Vector2i{X: 0, Y: 0})
-	tree.AddApp("app1")
+	tree.AddApp(1, "app1")
 
 	if tree.LastFocusedContainer.AppId != "app1" {
 		t.Errorf("Last focused app ID is not app1 and instead is %s", tree.LastFocusedContainer.AppId)
 	}
-	if tree.LastFocusedContainer.leafID == 0 {
-		t.Error("Didn't update leaf ID of last focused app")
+	if tree.LastFocusedContainer.Window != 1 {
+		t.Error("Didn't update window of last focused app")
+	}
+	if tree.FindApp(1) != tree.LastFocusedContainer {
+		t.Error("Didn't index the new leaf")
+	}
+	if err := checkNode(&tree.Root, nil); err != nil {
+		t.Errorf("Invalid tree structure: %s", err)
+	}
+}
+
+// Multiple windows of the same app must not collide
+func TestBTreeSameAppId(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 0, Y: 0})
+	tree.AddApp(1, "terminal")
+	tree.AddApp(2, "terminal")
+
+	first, second := tree.FindApp(1), tree.FindApp(2)
+	if first == nil || second == nil || first == second {
+		t.Fatalf("Windows collide: %+v, %+v", first, second)
+	}
+	if first.Window != 1 || second.Window != 2 {
+		t.Errorf("Found wrong leaves: %+v, %+v", first, second)
+	}
+	if leaves := tree.FindAppsById("terminal"); len(leaves) != 2 {
+		t.Errorf("Expected two terminals, got %+v", leaves)
+	}
+
+	tree.RemoveApp(1, true)
+	if tree.FindApp(1) != nil {
+		t.Errorf("Window 1 still in tree after removal")
+	}
+	if tree.FindApp(2) != second {
+		t.Errorf("Lost window 2 after removing window 1")
+	}
+}
+
+// Nesting depth must not be limited and removed windows must be able to come back
+func TestBTreeDeepNesting(t *testing.T) {
+	tree := nestedTree(200)
+	for i := 1; i <= 200; i++ {
+		if leaf := tree.FindApp(WindowID(i)); leaf == nil || leaf.Window != WindowID(i) {
+			t.Fatalf("Window %d not found, got %+v", i, leaf)
+		}
 	}
-	if err := checkNode(&tree.Root, math.MaxInt, math.MinInt); err != nil {
+	for i := 0; i < 1000; i++ {
+		tree.RemoveApp(200, true)
+		tree.AddApp(200, "app")
+	}
+	if err := checkNode(&tree.Root, nil); err != nil {
 		t.Errorf("Invalid tree structure: %s", err)
 	}
+	if tree.FindApp(200) != tree.LastFocusedContainer {
+		t.Errorf("Re-added window not focused")
+	}
+}
+
+// Builds a tree by adding the given amount of windows, each nested one level deeper than the last
+func nestedTree(windows int) *Tree {
+	tree := NewTree(generaldata.Vector2i{X: 1920, Y: 1080})
+	for i := 1; i <= windows; i++ {
+		tree.AddApp(WindowID(i), "app")
+	}
+	return &tree
+}
+
+func benchmarkFindApp(b *testing.B, windows int) {
+	tree := nestedTree(windows)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if tree.FindApp(WindowID(i%windows+1)) == nil {
+			b.Fatalf("Window %d not found", i%windows+1)
+		}
+	}
+}
+
+func BenchmarkFindApp8(b *testing.B)  { benchmarkFindApp(b, 8) }
+func BenchmarkFindApp32(b *testing.B) { benchmarkFindApp(b, 32) }
+func BenchmarkFindApp60(b *testing.B) { benchmarkFindApp(b, 60) }
+
+// Deeper than the old ID range scheme could handle
+func BenchmarkFindApp500(b *testing.B) { benchmarkFindApp(b, 500) }
+
+// Remove the deepest window and add it back again
+func BenchmarkRemoveAddApp(b *testing.B) {
+	tree := nestedTree(32)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.RemoveApp(32, true)
+		tree.AddApp(32, "app")
+	}
 }
@@ -0,0 +1,169 @@
+package tiler
+
+// How a container presents its children
+type ContainerMode int
+
+const (
+	// Children are placed next to each other according to the direction of the branch
+	ContainerModeSplit = ContainerMode(iota)
+	// Children share the whole area, only one of them is visible at a time
+	ContainerModeTabbed
+	// Same as tabbed, but with the titles stacked on top of each other instead of next to each other
+	// TODO: Geometrically identical to tabbed until there are title bars
+	ContainerModeStacked
+)
+
+// Change the mode of the container around the last focused container
+// A container is the chain of nested branches directly above the leaf that share the same mode
+// and, if split, the same direction. All of them get switched together so a container of several
+// windows always behaves as a single unit
+// Returns false if the focused leaf isn't inside any container
+func (t *Tree) SetContainerMode(mode ContainerMode) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	return t.setContainerMode(mode)
+}
+
+// Same as SetContainerMode, but expects the caller to already hold the lock
+func (t *Tree) setContainerMode(mode ContainerMode) bool {
+	leaf := t.LastFocusedContainer
+	if leaf == nil || leaf.parent == nil {
+		return false
+	}
+	t.remember()
+	for _, branch := range leaf.parent.containerBranches() {
+		branch.Mode = mode
+	}
+	// Make sure the focused leaf ends up as the visible tab
+	t.focusLeaf(leaf)
+	return true
+}
+
+// Focus the next or previous tab of the closest tabbed or stacked container around the last focused container
+// With the monocle layout all windows count as tabs
+// Wraps around at both ends
+// Returns the newly focused leaf or nil if the focused leaf isn't inside a tabbed or stacked container
+func (t *Tree) CycleTab(forward bool) *Leaf {
+	t.beginChange()
+	defer t.endChange()
+
+	leaf := t.LastFocusedContainer
+	if leaf == nil {
+		return nil
+	}
+	if _, ok := t.layout.(*Monocle); ok {
+		tabs := []Node{}
+		t.Root.walkLeaves(func(other *Leaf) {
+			if !other.IsEmpty || other == leaf {
+				tabs = append(tabs, Node{Type: NodeTypeLeaf, Leaf: other})
+			}
+		})
+		target := cycle(tabs, leaf, forward).visibleLeaf()
+		t.focusLeaf(target)
+		return target
+	}
+	var container *Branch
+	for branch := leaf.parent; branch != nil; branch = branch.parent {
+		if branch.Mode != ContainerModeSplit {
+			container = branch
+			break
+		}
+	}
+	if container == nil {
+		return nil
+	}
+
+	target := cycle(container.containerTop().tabs(), leaf, forward).visibleLeaf()
+	t.focusLeaf(target)
+	return target
+}
+
+// Get the tab after or before the one containing the leaf, wrapping around at both ends
+func cycle(tabs []Node, leaf *Leaf, forward bool) *Node {
+	current := 0
+	for i, tab := range tabs {
+		if tab.containsLeaf(leaf) {
+			current = i
+			break
+		}
+	}
+	next := current + 1
+	if !forward {
+		next = current - 1 + len(tabs)
+	}
+	return &tabs[next%len(tabs)]
+}
+
+// Check if two branches belong to the same container
+func (b *Branch) sameContainer(other *Branch) bool {
+	if b.Mode != other.Mode {
+		return false
+	}
+	return b.Mode != ContainerModeSplit || b.Direction == other.Direction
+}
+
+// Get the outermost branch of the container this branch belongs to
+func (b *Branch) containerTop() *Branch {
+	top := b
+	for top.parent != nil && top.parent.sameContainer(top) {
+		top = top.parent
+	}
+	return top
+}
+
+// Get all branches belonging to the same container as this one
+func (b *Branch) containerBranches() []*Branch {
+	branches := []*Branch{}
+	var collect func(branch *Branch)
+	collect = func(branch *Branch) {
+		branches = append(branches, branch)
+		for _, child := range []Node{branch.ChildLeft, branch.ChildRight} {
+			if child.Type == NodeTypeBranch && child.Branch.sameContainer(branch) {
+				collect(child.Branch)
+			}
+		}
+	}
+	collect(b.containerTop())
+	return branches
+}
+
+// Get the children of a container in left to right order
+// Nested branches of the same container are flattened into their children
+func (b *Branch) tabs() []Node {
+	tabs := []Node{}
+	for _, child := range []Node{b.ChildLeft, b.ChildRight} {
+		if child.Type == NodeTypeBranch && child.Branch.sameContainer(b) {
+			tabs = append(tabs, child.Branch.tabs()...)
+		} else {
+			tabs = append(tabs, child)
+		}
+	}
+	return tabs
+}
+
+// Check if a leaf is this node or somewhere below it
+func (n Node) containsLeaf(leaf *Leaf) bool {
+	child := Node{Type: NodeTypeLeaf, Leaf: leaf}
+	for branch := leaf.parent; ; branch = branch.parent {
+		if sameNode(n, child) {
+			return true
+		}
+		if branch == nil {
+			return false
+		}
+		child = Node{Type: NodeTypeBranch, Branch: branch}
+	}
+}
+
+// Get the leaf that is currently shown for a node
+// Follows the visible tab through tabbed and stacked containers and the left child through splits
+func (n *Node) visibleLeaf() *Leaf {
+	if n.Type == NodeTypeLeaf {
+		return n.Leaf
+	}
+	if n.Branch.Mode != ContainerModeSplit && n.Branch.showRight {
+		return n.Branch.ChildRight.visibleLeaf()
+	}
+	return n.Branch.ChildLeft.visibleLeaf()
+}
@@ -0,0 +1,155 @@
+package tiler
+
+import (
+	"slices"
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func visibleAppIds(tree *Tree) []string {
+	appIds := []string{}
+	for _, geometry := range tree.Arrange() {
+		if geometry.Visible {
+			appIds = append(appIds, geometry.Leaf.AppId)
+		}
+	}
+	return appIds
+}
+
+func TestContainerTabbed(t *testing.T) {
+	tree, leaves := threeAppTree()
+	tree.focusLeaf(leaves["b"])
+
+	if !tree.SetContainerMode(ContainerModeTabbed) {
+		t.Fatalf("Failed to switch container of b to tabbed")
+	}
+	for _, geometry := range tree.Arrange() {
+		if geometry.Leaf.AppId == "a" {
+			continue
+		}
+		if geometry.Area != rect(50, 0, 50, 100) {
+			t.Errorf("%s: expected the full container area, got %+v", geometry.Leaf.AppId, geometry.Area)
+		}
+	}
+	if appIds := visibleAppIds(tree); len(appIds) != 2 || appIds[1] != "b" {
+		t.Errorf("Expected a and b visible, got %v", appIds)
+	}
+
+	// Hidden tabs can't be reached by directional focus
+	if down := tree.FindNeighbours(leaves["b"]).Down; down != nil {
+		t.Errorf("Expected nothing below b, got %+v", down)
+	}
+
+	if leaf := tree.CycleTab(true); leaf != leaves["c"] {
+		t.Fatalf("Expected c as next tab, got %+v", leaf)
+	}
+	if appIds := visibleAppIds(tree); len(appIds) != 2 || appIds[1] != "c" {
+		t.Errorf("Expected a and c visible, got %v", appIds)
+	}
+	if right := tree.FindNeighbours(leaves["a"]).Right; right != leaves["c"] {
+		t.Errorf("Expected the visible tab c right of a, got %+v", right)
+	}
+	if leaf := tree.CycleTab(true); leaf != leaves["b"] {
+		t.Errorf("Expected to wrap around to b, got %+v", leaf)
+	}
+
+	// Focusing a hidden tab directly reveals it
+	tree.FocusApp(3)
+	if appIds := visibleAppIds(tree); len(appIds) != 2 || appIds[1] != "c" {
+		t.Errorf("Expected c to be revealed by focusing it, got %v", appIds)
+	}
+
+	if !tree.SetContainerMode(ContainerModeSplit) {
+		t.Fatalf("Failed to switch container of c back to split")
+	}
+	if appIds := visibleAppIds(tree); len(appIds) != 3 {
+		t.Errorf("Expected all apps visible again, got %v", appIds)
+	}
+}
+
+// Nested branches with the same direction form a single container
+func TestContainerNested(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 90, Y: 90})
+	a, b, c := appLeaf(1, "a"), appLeaf(2, "b"), appLeaf(3, "c")
+	tree.Root = Node{
+		Type: NodeTypeBranch,
+		Branch: &Branch{
+			Direction:  DirectionHorizontal,
+			AspectLeft: 50,
+			ChildLeft:  a,
+			ChildRight: Node{
+				Type: NodeTypeBranch,
+				Branch: &Branch{
+					Direction:  DirectionHorizontal,
+					AspectLeft: 50,
+					ChildLeft:  b,
+					ChildRight: c,
+				},
+			},
+		},
+	}
+	tree.LastFocusedContainer = c.Leaf
+	tree.relink()
+
+	if !tree.SetContainerMode(ContainerModeStacked) {
+		t.Fatalf("Failed to switch container of c to stacked")
+	}
+	if tree.Root.Branch.Mode != ContainerModeStacked {
+		t.Errorf("Outer branch of the container not switched")
+	}
+	for _, expected := range []string{"a", "b", "c"} {
+		if leaf := tree.CycleTab(true); leaf.AppId != expected {
+			t.Errorf("Expected tab %s, got %+v", expected, leaf)
+		}
+		if appIds := visibleAppIds(&tree); len(appIds) != 1 || appIds[0] != expected {
+			t.Errorf("Expected only %s visible, got %v", expected, appIds)
+		}
+	}
+	if leaf := tree.CycleTab(false); leaf.AppId != "b" {
+		t.Errorf("Expected previous tab b, got %+v", leaf)
+	}
+}
+
+// Windows added inside a tabbed container become another tab instead of splitting the focused one
+func TestContainerAddTab(t *testing.T) {
+	tree, leaves := threeAppTree()
+	tree.focusLeaf(leaves["b"])
+	if !tree.SetContainerMode(ContainerModeTabbed) {
+		t.Fatalf("Failed to switch container of b to tabbed")
+	}
+
+	tree.AddApp(4, "d")
+	for _, geometry := range tree.Arrange() {
+		if geometry.Leaf.AppId != "a" && geometry.Area != rect(50, 0, 50, 100) {
+			t.Errorf("%s: expected the full container area, got %+v", geometry.Leaf.AppId, geometry.Area)
+		}
+	}
+	if appIds := visibleAppIds(tree); len(appIds) != 2 || appIds[1] != "d" {
+		t.Errorf("Expected a and the new tab d visible, got %v", appIds)
+	}
+	// The new tab goes right after the focused one
+	tabs := []string{}
+	for range 3 {
+		tabs = append(tabs, tree.CycleTab(true).AppId)
+	}
+	if !slices.Equal(tabs, []string{"c", "b", "d"}) {
+		t.Errorf("Expected the tabs b, d, c, got %v when cycling from d", tabs)
+	}
+	if err := checkNode(&tree.Root, nil); err != nil {
+		t.Errorf("Invalid tree structure: %s", err)
+	}
+}
+
+func TestContainerOutsideTabs(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.AddApp(1, "a")
+	if tree.SetContainerMode(ContainerModeTabbed) {
+		t.Errorf("Switched the mode of a window without container")
+	}
+
+	tree.AddApp(2, "b")
+	if leaf := tree.CycleTab(true); leaf != nil {
+		t.Errorf("Cycled tabs of a split container, got %+v", leaf)
+	}
+}
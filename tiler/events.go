@@ -0,0 +1,141 @@
+package tiler
+
+import (
+	"fmt"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Kind of change that happened to a tree
+type EventType int
+
+const (
+	// A window was put into a leaf
+	EventWindowAdded = EventType(iota)
+	// A window was taken out of the tree
+	EventWindowRemoved
+	// A window moved to another leaf
+	EventWindowMoved
+	// The share of a branch or automatic layout changed
+	EventRatioChanged
+	// Another leaf became the last focused container
+	EventFocusChanged
+	// The area or visibility of a window changed, for whatever reason
+	EventGeometryChanged
+)
+
+// Names of the event types, as used outside of the compositor
+var eventTypeNames = map[EventType]string{
+	EventWindowAdded:     "window_added",
+	EventWindowRemoved:   "window_removed",
+	EventWindowMoved:     "window_moved",
+	EventRatioChanged:    "ratio_changed",
+	EventFocusChanged:    "focus_changed",
+	EventGeometryChanged: "geometry_changed",
+}
+
+func (e EventType) String() string {
+	if name, ok := eventTypeNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(e))
+}
+
+// A single change to a tree
+type Event struct {
+	Type   EventType
+	Window WindowID // Window the change is about. Empty for ratio changes and focusing empty leaves
+	Branch *Branch  // Branch whose aspect changed. Only set for ratio changes of the tree itself
+
+	// Geometry before and after the change. Only set for geometry changes
+	// Windows that weren't arranged before have a zero OldArea
+	OldArea    generaldata.Rect
+	NewArea    generaldata.Rect
+	OldVisible bool
+	NewVisible bool
+}
+
+// Function receiving all events caused by a single operation on the tree
+// Called after the tree has been unlocked, so it may use the tree again
+type Subscriber func(events []Event)
+
+// Get notified about every change of the tree
+// Returns a function that ends the subscription
+func (t *Tree) Subscribe(subscriber Subscriber) func() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.subscribers == nil {
+		t.subscribers = map[int]Subscriber{}
+	}
+	t.lastSubscriber++
+	id := t.lastSubscriber
+	t.subscribers[id] = subscriber
+	return func() {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+
+		delete(t.subscribers, id)
+	}
+}
+
+// Lock the tree for an operation that may change it
+// Remembers the current geometry, so the change can be described afterwards
+func (t *Tree) beginChange() {
+	t.lock.Lock()
+	if len(t.subscribers) > 0 {
+		t.before = t.arrange(false)
+	}
+}
+
+// Unlock the tree after an operation and notify all subscribers about what changed
+// Geometry changes are found by comparing with the geometry from beginChange
+func (t *Tree) endChange() {
+	if len(t.subscribers) == 0 {
+		t.pending = nil
+		t.lock.Unlock()
+		return
+	}
+
+	events := t.pending
+	before := map[WindowID]LeafGeometry{}
+	for _, geometry := range t.before {
+		before[geometry.Leaf.Window] = geometry
+	}
+	for _, geometry := range t.arrange(false) {
+		old, ok := before[geometry.Leaf.Window]
+		if ok && old.Area == geometry.Area && old.Visible == geometry.Visible {
+			continue
+		}
+		events = append(events, Event{
+			Type:       EventGeometryChanged,
+			Window:     geometry.Leaf.Window,
+			OldArea:    old.Area,
+			NewArea:    geometry.Area,
+			OldVisible: old.Visible,
+			NewVisible: geometry.Visible,
+		})
+	}
+	subscribers := make([]Subscriber, 0, len(t.subscribers))
+	for _, subscriber := range t.subscribers {
+		subscribers = append(subscribers, subscriber)
+	}
+	t.pending = nil
+	t.before = nil
+	t.lock.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+	for _, subscriber := range subscribers {
+		subscriber(events)
+	}
+}
+
+// Record a change to announce once the current operation is done
+// Expects the caller to hold the lock
+func (t *Tree) emit(event Event) {
+	if len(t.subscribers) > 0 {
+		t.pending = append(t.pending, event)
+	}
+}
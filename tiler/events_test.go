@@ -0,0 +1,111 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Collect the events of every operation on a tree, one slice per operation
+func recordEvents(tree *Tree) *[][]Event {
+	batches := [][]Event{}
+	tree.Subscribe(func(events []Event) {
+		batches = append(batches, events)
+	})
+	return &batches
+}
+
+func countEvents(events []Event, eventType EventType) int {
+	count := 0
+	for _, event := range events {
+		if event.Type == eventType {
+			count++
+		}
+	}
+	return count
+}
+
+func TestEventsAddRemove(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.AddApp(1, "a")
+	batches := recordEvents(&tree)
+
+	tree.AddApp(2, "b")
+	if len(*batches) != 1 {
+		t.Fatalf("Expected one batch per operation, got %d", len(*batches))
+	}
+	events := (*batches)[0]
+	if events[0].Type != EventWindowAdded || events[0].Window != 2 {
+		t.Errorf("Expected window 2 to be added first, got %+v", events[0])
+	}
+	if countEvents(events, EventFocusChanged) != 1 {
+		t.Errorf("Expected a focus change, got %+v", events)
+	}
+	// Both windows got new areas, the first one shrank and the second one is new
+	for _, event := range events {
+		if event.Type != EventGeometryChanged {
+			continue
+		}
+		switch event.Window {
+		case 1:
+			if event.OldArea != rect(0, 0, 100, 100) || event.NewArea != rect(0, 0, 100, 50) {
+				t.Errorf("Wrong geometry change for window 1: %+v", event)
+			}
+		case 2:
+			if event.OldVisible || !event.NewVisible || event.NewArea != rect(0, 50, 100, 50) {
+				t.Errorf("Wrong geometry change for window 2: %+v", event)
+			}
+		}
+	}
+	if count := countEvents(events, EventGeometryChanged); count != 2 {
+		t.Errorf("Expected 2 geometry changes, got %d", count)
+	}
+
+	tree.RemoveApp(2, true)
+	events = (*batches)[1]
+	if countEvents(events, EventWindowRemoved) != 1 || countEvents(events, EventGeometryChanged) != 1 {
+		t.Errorf("Expected the removal and window 1 growing back, got %+v", events)
+	}
+}
+
+func TestEventsOnlyAffected(t *testing.T) {
+	tree, leaves := threeAppTree()
+	batches := recordEvents(tree)
+
+	if !tree.ResizeEdge(2, SideDown, 10) {
+		t.Fatalf("Failed to resize")
+	}
+	events := (*batches)[0]
+	if countEvents(events, EventRatioChanged) != 1 || events[0].Branch != leaves["b"].parent {
+		t.Errorf("Expected a ratio change of the branch around b, got %+v", events)
+	}
+	// a isn't touched by the split between b and c
+	for _, event := range events {
+		if event.Type == EventGeometryChanged && event.Window == 1 {
+			t.Errorf("Got geometry change for unaffected window: %+v", event)
+		}
+	}
+
+	// Operations that change nothing stay silent, a is focused already
+	tree.FocusApp(leaves["a"].Window)
+	if len(*batches) != 1 {
+		t.Errorf("Expected no events for refocusing, got %+v", (*batches)[1:])
+	}
+}
+
+func TestEventsUnsubscribe(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	calls := 0
+	unsubscribe := tree.Subscribe(func(events []Event) {
+		calls++
+		// Subscribers are called without the lock held
+		tree.Arrange()
+	})
+
+	tree.AddApp(1, "a")
+	unsubscribe()
+	tree.AddApp(2, "b")
+	if calls != 1 {
+		t.Errorf("Expected exactly one call before unsubscribing, got %d", calls)
+	}
+}
@@ -0,0 +1,165 @@
+package tiler
+
+import (
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// A side of a leaf, used for directional navigation
+type Side int
+
+const (
+	SideUp = Side(iota)
+	SideDown
+	SideLeft
+	SideRight
+)
+
+// Find the closest non-empty leaf on each side of the given leaf
+// A leaf only counts as neighbour if it lies completely on that side and overlaps with the given leaf on the other axis
+// Closer leaves win, ties are broken by the larger overlap
+func (t *Tree) FindNeighbours(leaf *Leaf) LeafNeighbours {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.findNeighbours(leaf)
+}
+
+// Same as FindNeighbours, but expects the caller to already hold the lock
+func (t *Tree) findNeighbours(leaf *Leaf) LeafNeighbours {
+	geometries := t.arrange(true)
+
+	var own *generaldata.Rect
+	for i := range geometries {
+		if geometries[i].Leaf == leaf {
+			own = &geometries[i].Area
+			break
+		}
+	}
+	if own == nil {
+		// Leaf not in tree, so it doesn't have any neighbours
+		return LeafNeighbours{}
+	}
+
+	return LeafNeighbours{
+		Up:    closestOnSide(leaf, *own, geometries, SideUp),
+		Down:  closestOnSide(leaf, *own, geometries, SideDown),
+		Left:  closestOnSide(leaf, *own, geometries, SideLeft),
+		Right: closestOnSide(leaf, *own, geometries, SideRight),
+	}
+}
+
+// Move the focus to the neighbour of the last focused container on the given side
+// Returns the newly focused leaf or nil if there is no neighbour on that side
+func (t *Tree) MoveFocus(side Side) *Leaf {
+	t.beginChange()
+	defer t.endChange()
+
+	target := t.findNeighbours(t.LastFocusedContainer).OnSide(side)
+	if target == nil {
+		return nil
+	}
+	t.focusLeaf(target)
+	return target
+}
+
+// Mark the leaf containing the given window as last focused
+// Returns false if the window isn't in the tree
+func (t *Tree) FocusApp(window WindowID) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	return t.focusApp(window)
+}
+
+// Same as FocusApp, but expects the caller to already hold the lock
+func (t *Tree) focusApp(window WindowID) bool {
+	leaf := t.findApp(window)
+	if leaf == nil {
+		return false
+	}
+	t.focusLeaf(leaf)
+	return true
+}
+
+// Get the neighbour on the given side
+func (n LeafNeighbours) OnSide(side Side) *Leaf {
+	switch side {
+	case SideUp:
+		return n.Up
+	case SideDown:
+		return n.Down
+	case SideLeft:
+		return n.Left
+	case SideRight:
+		return n.Right
+	}
+	return nil
+}
+
+// Update both focus pointers to the given leaf
+// Also makes sure the leaf is the visible tab of all tabbed and stacked containers around it
+// Expects the caller to hold the lock
+func (t *Tree) focusLeaf(leaf *Leaf) {
+	if t.LastFocusedContainer != leaf {
+		t.emit(Event{Type: EventFocusChanged, Window: leaf.Window})
+	}
+	t.LastFocusedContainer = leaf
+	t.LastFocusedParent = leaf.parent
+
+	child := Node{Type: NodeTypeLeaf, Leaf: leaf}
+	for branch := leaf.parent; branch != nil; branch = branch.parent {
+		if branch.Mode != ContainerModeSplit {
+			branch.showRight = sameNode(branch.ChildRight, child)
+		}
+		child = Node{Type: NodeTypeBranch, Branch: branch}
+	}
+}
+
+// Get the left-most leaf of a node
+func (n *Node) firstLeaf() *Leaf {
+	if n.Type == NodeTypeBranch && n.Branch != nil {
+		return n.Branch.ChildLeft.firstLeaf()
+	}
+	return n.Leaf
+}
+
+// Pick the closest non-empty leaf on one side of an area
+func closestOnSide(leaf *Leaf, area generaldata.Rect, candidates []LeafGeometry, side Side) *Leaf {
+	var best *Leaf
+	bestDistance, bestOverlap := 0, 0
+	for _, candidate := range candidates {
+		if candidate.Leaf == leaf || candidate.Leaf.IsEmpty || !candidate.Visible {
+			continue
+		}
+		other := candidate.Area
+		var distance, overlap int
+		switch side {
+		case SideUp:
+			distance = area.Position.Y - (other.Position.Y + other.Size.Y)
+			overlap = spanOverlap(area.Position.X, area.Size.X, other.Position.X, other.Size.X)
+		case SideDown:
+			distance = other.Position.Y - (area.Position.Y + area.Size.Y)
+			overlap = spanOverlap(area.Position.X, area.Size.X, other.Position.X, other.Size.X)
+		case SideLeft:
+			distance = area.Position.X - (other.Position.X + other.Size.X)
+			overlap = spanOverlap(area.Position.Y, area.Size.Y, other.Position.Y, other.Size.Y)
+		case SideRight:
+			distance = other.Position.X - (area.Position.X + area.Size.X)
+			overlap = spanOverlap(area.Position.Y, area.Size.Y, other.Position.Y, other.Size.Y)
+		}
+		if distance < 0 || overlap <= 0 {
+			continue
+		}
+		if best == nil || distance < bestDistance || (distance == bestDistance && overlap > bestOverlap) {
+			best = candidate.Leaf
+			bestDistance = distance
+			bestOverlap = overlap
+		}
+	}
+	return best
+}
+
+// How much two one-dimensional spans overlap
+func spanOverlap(startA, lengthA, startB, lengthB int) int {
+	return min(startA+lengthA, startB+lengthB) - max(startA, startB)
+}
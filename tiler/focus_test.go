@@ -0,0 +1,91 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Builds a tree with "a" on the left and "b" above "c" on the right
+func threeAppTree() (*Tree, map[string]*Leaf) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	a, b, c := appLeaf(1, "a"), appLeaf(2, "b"), appLeaf(3, "c")
+	tree.Root = Node{
+		Type: NodeTypeBranch,
+		Branch: &Branch{
+			Direction:  DirectionHorizontal,
+			AspectLeft: 50,
+			ChildLeft:  a,
+			ChildRight: Node{
+				Type: NodeTypeBranch,
+				Branch: &Branch{
+					Direction:  DirectionVertical,
+					AspectLeft: 50,
+					ChildLeft:  b,
+					ChildRight: c,
+				},
+			},
+		},
+	}
+	tree.LastFocusedContainer = a.Leaf
+	tree.relink()
+	return &tree, map[string]*Leaf{"a": a.Leaf, "b": b.Leaf, "c": c.Leaf}
+}
+
+func TestFindNeighbours(t *testing.T) {
+	tree, leaves := threeAppTree()
+
+	neighbours := tree.FindNeighbours(leaves["c"])
+	if neighbours.Up != leaves["b"] {
+		t.Errorf("Expected b above c, got %+v", neighbours.Up)
+	}
+	if neighbours.Left != leaves["a"] {
+		t.Errorf("Expected a left of c, got %+v", neighbours.Left)
+	}
+	if neighbours.Right != nil || neighbours.Down != nil {
+		t.Errorf("Expected nothing right of or below c, got %+v", neighbours)
+	}
+
+	// Both b and c touch a, b comes first in the tree
+	if right := tree.FindNeighbours(leaves["a"]).Right; right != leaves["b"] {
+		t.Errorf("Expected b right of a, got %+v", right)
+	}
+}
+
+func TestMoveFocus(t *testing.T) {
+	tree, leaves := threeAppTree()
+	tree.focusLeaf(leaves["c"])
+
+	if focused := tree.MoveFocus(SideUp); focused != leaves["b"] {
+		t.Fatalf("Expected focus to move to b, got %+v", focused)
+	}
+	if tree.LastFocusedContainer != leaves["b"] {
+		t.Errorf("Last focused container not updated")
+	}
+	if tree.LastFocusedParent != tree.Root.Branch.ChildRight.Branch {
+		t.Errorf("Last focused parent not updated")
+	}
+	if focused := tree.MoveFocus(SideUp); focused != nil {
+		t.Errorf("Expected no leaf above b, got %+v", focused)
+	}
+	if tree.LastFocusedContainer != leaves["b"] {
+		t.Errorf("Focus moved despite there being no neighbour")
+	}
+}
+
+// Removing the only app must pop the split and move the focus back to the remaining leaf
+func TestRemoveAppPopParent(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.AddApp(1, "app1")
+	tree.RemoveApp(1, true)
+
+	if tree.Root.Type != NodeTypeLeaf {
+		t.Fatalf("Expected the split to be popped")
+	}
+	if tree.LastFocusedContainer != tree.Root.Leaf || tree.LastFocusedParent != nil {
+		t.Errorf("Focus not moved to the remaining leaf")
+	}
+	if tree.FindApp(1) != nil {
+		t.Errorf("app1 still in tree")
+	}
+}
@@ -0,0 +1,98 @@
+package tiler
+
+import (
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Empty space left around tiled windows, in pixels
+type Gaps struct {
+	Inner       int  // Space between two windows
+	OuterTop    int  // Space between the windows and the top border of the tree
+	OuterRight  int  // Space between the windows and the right border of the tree
+	OuterBottom int  // Space between the windows and the bottom border of the tree
+	OuterLeft   int  // Space between the windows and the left border of the tree
+	Smart       bool // Leave out all gaps while there is only a single window
+}
+
+// Replace the gaps of the tree
+// Negative gaps are treated as 0
+func (t *Tree) SetGaps(gaps Gaps) {
+	t.beginChange()
+	defer t.endChange()
+
+	gaps.Inner = max(gaps.Inner, 0)
+	gaps.OuterTop = max(gaps.OuterTop, 0)
+	gaps.OuterRight = max(gaps.OuterRight, 0)
+	gaps.OuterBottom = max(gaps.OuterBottom, 0)
+	gaps.OuterLeft = max(gaps.OuterLeft, 0)
+	t.gaps = gaps
+}
+
+// Get the gaps currently used by the tree
+func (t *Tree) Gaps() Gaps {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.gaps
+}
+
+// Get the area windows can be placed in without covering the outer gaps
+func (t *Tree) UsableArea() generaldata.Rect {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.area()
+}
+
+// Check if gaps are left out because of smart gaps
+// Expects the caller to hold the lock
+func (t *Tree) gapsSuppressed() bool {
+	return t.gaps.Smart && len(t.leaves) <= 1
+}
+
+// Get the area windows get placed in, which is the resolution minus the outer gaps
+// Expects the caller to hold the lock
+func (t *Tree) area() generaldata.Rect {
+	area := generaldata.Rect{Size: t.Resolution}
+	if t.gapsSuppressed() {
+		return area
+	}
+	area.Position.X += t.gaps.OuterLeft
+	area.Position.Y += t.gaps.OuterTop
+	area.Size.X = max(area.Size.X-t.gaps.OuterLeft-t.gaps.OuterRight, 0)
+	area.Size.Y = max(area.Size.Y-t.gaps.OuterTop-t.gaps.OuterBottom, 0)
+	return area
+}
+
+// Shrink the areas of arranged leaves so there is an inner gap between any two of them
+// Edges lying on the border of the given area are left alone, those only get the outer gaps
+// Expects the caller to hold the lock
+func (t *Tree) applyInnerGaps(area generaldata.Rect, geometries []LeafGeometry) {
+	if t.gapsSuppressed() || t.gaps.Inner == 0 {
+		return
+	}
+	// Right and bottom edges give up the smaller half, so two neighbours always end up exactly Inner apart
+	before := t.gaps.Inner - t.gaps.Inner/2
+	after := t.gaps.Inner / 2
+	for i := range geometries {
+		rect := &geometries[i].Area
+		left, top := 0, 0
+		right, bottom := 0, 0
+		if rect.Position.X > area.Position.X {
+			left = before
+		}
+		if rect.Position.Y > area.Position.Y {
+			top = before
+		}
+		if rect.Position.X+rect.Size.X < area.Position.X+area.Size.X {
+			right = after
+		}
+		if rect.Position.Y+rect.Size.Y < area.Position.Y+area.Size.Y {
+			bottom = after
+		}
+		rect.Position.X += left
+		rect.Position.Y += top
+		rect.Size.X = max(rect.Size.X-left-right, 0)
+		rect.Size.Y = max(rect.Size.Y-top-bottom, 0)
+	}
+}
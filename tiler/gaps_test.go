@@ -0,0 +1,59 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func TestGaps(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.AddApp(1, "a")
+	tree.AddApp(2, "b")
+	tree.SetGaps(Gaps{Inner: 10, OuterTop: 5, OuterRight: 20, OuterBottom: 20, OuterLeft: 20})
+
+	geometries := tree.Arrange()
+	expected := []generaldata.Rect{rect(20, 5, 60, 32), rect(20, 47, 60, 33)}
+	for i, geometry := range geometries {
+		if geometry.Area != expected[i] {
+			t.Errorf("%s: expected %+v, got %+v", geometry.Leaf.AppId, expected[i], geometry.Area)
+		}
+	}
+
+	// The gap between the windows is part of their border
+	if window, sides := tree.EdgesAt(generaldata.Vector2i{X: 50, Y: 42}, 2); window != 1 || len(sides) != 1 || sides[0] != SideDown {
+		t.Errorf("Expected the bottom edge of a in the gap, got %d with %v", window, sides)
+	}
+
+	if area := tree.UsableArea(); area != rect(20, 5, 60, 75) {
+		t.Errorf("Expected usable area inside the outer gaps, got %+v", area)
+	}
+
+	// Resizing works within the area inside the outer gaps
+	if !tree.MoveEdge(1, SideDown, 5+60) {
+		t.Fatalf("Failed to move edge")
+	}
+	if aspect := tree.Root.Branch.AspectLeft; aspect != 80 {
+		t.Errorf("Expected aspect 80, got %d", aspect)
+	}
+}
+
+func TestGapsSmart(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.SetGaps(Gaps{Inner: 10, OuterTop: 10, OuterRight: 10, OuterBottom: 10, OuterLeft: 10, Smart: true})
+	tree.AddApp(1, "a")
+
+	if area := tree.Arrange()[0].Area; area != rect(0, 0, 100, 100) {
+		t.Errorf("Expected no gaps around a single window, got %+v", area)
+	}
+
+	tree.AddApp(2, "b")
+	if area := tree.Arrange()[0].Area; area != rect(10, 10, 80, 35) {
+		t.Errorf("Expected gaps with two windows, got %+v", area)
+	}
+
+	tree.SetGaps(Gaps{Inner: -5})
+	if gaps := tree.Gaps(); gaps.Inner != 0 {
+		t.Errorf("Negative gap not clamped, got %+v", gaps)
+	}
+}
@@ -0,0 +1,95 @@
+package tiler
+
+import (
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Position and size of a leaf once the tree has been arranged
+type LeafGeometry struct {
+	Leaf    *Leaf
+	Area    generaldata.Rect // Relative to the top left corner of the tree
+	Visible bool             // False if the leaf is a hidden tab of a tabbed or stacked container
+}
+
+// Calculate the area of every non-empty leaf within the tree's resolution, minus the gaps
+// Empty leaves still take up their share of the space, they just don't show up in the result
+// The left/top child of a split gets its share rounded down, the right/bottom child gets the rest
+// That way the leaves always cover the full resolution without any gaps or overlaps
+// Tabs of tabbed and stacked containers all get the full area of their container, but only one of them is visible
+// If an automatic layout is set, it places the windows in their left to right order instead
+// Windows with a maximum size smaller than their share are centered in it, see SetSizeHints
+func (t *Tree) Arrange() []LeafGeometry {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.arrange(false)
+}
+
+// Same as Arrange, but expects the caller to already hold the lock
+// Empty leaves are only included if includeEmpty is set and the tree isn't using an automatic layout
+func (t *Tree) arrange(includeEmpty bool) []LeafGeometry {
+	area := t.area()
+	geometries := []LeafGeometry{}
+	if t.layout == nil {
+		t.Root.arrange(area, includeEmpty, true, t.hints, &geometries)
+		t.applyInnerGaps(area, geometries)
+		t.applyMaxSizes(geometries)
+		return geometries
+	}
+
+	windows := []WindowID{}
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		if !leaf.IsEmpty {
+			windows = append(windows, leaf.Window)
+		}
+	})
+	for _, geometry := range t.layout.Arrange(windows, area) {
+		if leaf := t.findApp(geometry.Window); leaf != nil {
+			geometries = append(geometries, LeafGeometry{Leaf: leaf, Area: geometry.Area, Visible: true})
+		}
+	}
+	t.applyInnerGaps(area, geometries)
+	t.applyMaxSizes(geometries)
+	return geometries
+}
+
+// Recursively arrange a node and everything below it within the given area
+// Results are appended to out in left to right order, empty leaves only if includeEmpty is set
+func (n *Node) arrange(area generaldata.Rect, includeEmpty bool, visible bool, hints map[WindowID]SizeHints, out *[]LeafGeometry) {
+	switch n.Type {
+	case NodeTypeLeaf:
+		if n.Leaf != nil && (includeEmpty || !n.Leaf.IsEmpty) {
+			*out = append(*out, LeafGeometry{Leaf: n.Leaf, Area: area, Visible: visible})
+		}
+	case NodeTypeBranch:
+		if n.Branch == nil {
+			return
+		}
+		left, right := n.Branch.splitArea(area, hints)
+		leftVisible, rightVisible := visible, visible
+		if n.Branch.Mode != ContainerModeSplit {
+			leftVisible = visible && !n.Branch.showRight
+			rightVisible = visible && n.Branch.showRight
+		}
+		n.Branch.ChildLeft.arrange(left, includeEmpty, leftVisible, hints, out)
+		n.Branch.ChildRight.arrange(right, includeEmpty, rightVisible, hints, out)
+	}
+}
+
+// Split an area between the two children of a branch according to its direction and aspect
+// The split is moved if that is needed to respect the size hints of the windows below the branch
+// Tabs of tabbed and stacked containers share the whole area
+func (b *Branch) splitArea(area generaldata.Rect, hints map[WindowID]SizeHints) (generaldata.Rect, generaldata.Rect) {
+	if b.Mode != ContainerModeSplit {
+		// TODO: Leave space for title bars once there are decorations
+		return area, area
+	}
+	aspect := min(max(b.AspectLeft, 0), 100)
+	// Left child on top if split vertically, right child at the bottom
+	size := sizeAlong(area, b.Direction)
+	leftSize := size * aspect / 100
+	if len(hints) > 0 {
+		leftSize = b.limitSplit(size, leftSize, hints)
+	}
+	return splitSpan(area, b.Direction, leftSize)
+}
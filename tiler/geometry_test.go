@@ -0,0 +1,105 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func appLeaf(window WindowID, appId string) Node {
+	return Node{Type: NodeTypeLeaf, Leaf: &Leaf{Window: window, AppId: appId}}
+}
+
+func rect(x, y, w, h int) generaldata.Rect {
+	return generaldata.Rect{
+		Position: generaldata.Vector2i{X: x, Y: y},
+		Size:     generaldata.Vector2i{X: w, Y: h},
+	}
+}
+
+// An empty tree has nothing to arrange
+func TestArrangeEmpty(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 1920, Y: 1080})
+	if geometries := tree.Arrange(); len(geometries) != 0 {
+		t.Errorf("Expected no geometries, got %+v", geometries)
+	}
+}
+
+// The first window takes over the empty root leaf
+func TestArrangeInsert(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 1920, Y: 1081})
+	tree.AddApp(1, "app1")
+
+	geometries := tree.Arrange()
+	if len(geometries) != 1 {
+		t.Fatalf("Expected one geometry, got %+v", geometries)
+	}
+	if geometries[0].Leaf.AppId != "app1" {
+		t.Errorf("Expected app1, got %s", geometries[0].Leaf.AppId)
+	}
+	if expected := rect(0, 0, 1920, 1081); geometries[0].Area != expected {
+		t.Errorf("Expected %+v, got %+v", expected, geometries[0].Area)
+	}
+}
+
+// Empty leaves keep their share of the space
+func TestArrangeEmptyLeaf(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 1920, Y: 1081})
+	tree.AddApp(1, "app1")
+	tree.AddApp(2, "app2")
+	tree.RemoveApp(1, false)
+
+	geometries := tree.Arrange()
+	if len(geometries) != 1 {
+		t.Fatalf("Expected one geometry, got %+v", geometries)
+	}
+	if geometries[0].Leaf.AppId != "app2" {
+		t.Errorf("Expected app2, got %s", geometries[0].Leaf.AppId)
+	}
+	if expected := rect(0, 540, 1920, 541); geometries[0].Area != expected {
+		t.Errorf("Expected %+v, got %+v", expected, geometries[0].Area)
+	}
+}
+
+// Uneven splits must neither leave gaps nor overlap
+func TestArrangeRounding(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 1000, Y: 101})
+	tree.Root = Node{
+		Type: NodeTypeBranch,
+		Branch: &Branch{
+			Direction:  DirectionHorizontal,
+			AspectLeft: 33,
+			ChildLeft:  appLeaf(1, "left"),
+			ChildRight: Node{
+				Type: NodeTypeBranch,
+				Branch: &Branch{
+					Direction:  DirectionVertical,
+					AspectLeft: 50,
+					ChildLeft:  appLeaf(2, "top"),
+					ChildRight: appLeaf(3, "bottom"),
+				},
+			},
+		},
+	}
+	tree.relink()
+
+	expected := map[string]generaldata.Rect{
+		"left":   rect(0, 0, 330, 101),
+		"top":    rect(330, 0, 670, 50),
+		"bottom": rect(330, 50, 670, 51),
+	}
+	geometries := tree.Arrange()
+	if len(geometries) != len(expected) {
+		t.Fatalf("Expected %d geometries, got %+v", len(expected), geometries)
+	}
+	area := 0
+	for _, geometry := range geometries {
+		if geometry.Area != expected[geometry.Leaf.AppId] {
+			t.Errorf("%s: expected %+v, got %+v", geometry.Leaf.AppId, expected[geometry.Leaf.AppId], geometry.Area)
+		}
+		area += geometry.Area.Size.X * geometry.Area.Size.Y
+	}
+	if area != 1000*101 {
+		t.Errorf("Leaves cover %d pixels instead of %d", area, 1000*101)
+	}
+}
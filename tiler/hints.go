@@ -0,0 +1,129 @@
+package tiler
+
+import (
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Size limits a window asks for, like the min and max size of xdg toplevels
+// A dimension of 0 means there is no limit in that dimension
+type SizeHints struct {
+	Min generaldata.Vector2i
+	Max generaldata.Vector2i
+}
+
+// Set the size limits of a window
+// Splits give the window at least its minimum and at most its maximum size where the space allows it
+// Automatic layouts place windows without looking at the hints
+// Windows that get more than their maximum are centered in their area, those that get less than
+// their minimum are still given the smaller area, the compositor has to clip them to it
+// Zero hints remove the limits. Hints are kept while the window is out of the tree, so they have to be reset once the window is gone
+func (t *Tree) SetSizeHints(window WindowID, hints SizeHints) {
+	t.beginChange()
+	defer t.endChange()
+
+	if hints == (SizeHints{}) {
+		delete(t.hints, window)
+		return
+	}
+	if t.hints == nil {
+		t.hints = map[WindowID]SizeHints{}
+	}
+	t.hints[window] = hints
+}
+
+// Get the size limits of a window
+func (t *Tree) SizeHints(window WindowID) SizeHints {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.hints[window]
+}
+
+// Get the smallest and largest size a node can be arranged at, combining the hints of all windows below it
+// A maximum of 0 means there is no limit
+func (n *Node) sizeLimits(hints map[WindowID]SizeHints) (generaldata.Vector2i, generaldata.Vector2i) {
+	if n.Type == NodeTypeLeaf {
+		if n.Leaf.IsEmpty {
+			return generaldata.Vector2i{}, generaldata.Vector2i{}
+		}
+		return hints[n.Leaf.Window].Min, hints[n.Leaf.Window].Max
+	}
+	leftMin, leftMax := n.Branch.ChildLeft.sizeLimits(hints)
+	rightMin, rightMax := n.Branch.ChildRight.sizeLimits(hints)
+	// Across the split both children get the same size, the smaller one gets centered if needed
+	minSize := generaldata.Vector2i{X: max(leftMin.X, rightMin.X), Y: max(leftMin.Y, rightMin.Y)}
+	maxSize := generaldata.Vector2i{X: largerLimit(leftMax.X, rightMax.X), Y: largerLimit(leftMax.Y, rightMax.Y)}
+	if n.Branch.Mode != ContainerModeSplit {
+		return minSize, maxSize
+	}
+	// Along the split both children are next to each other
+	if n.Branch.Direction == DirectionVertical {
+		minSize.Y = leftMin.Y + rightMin.Y
+		maxSize.Y = limitSum(leftMax.Y, rightMax.Y)
+	} else {
+		minSize.X = leftMin.X + rightMin.X
+		maxSize.X = limitSum(leftMax.X, rightMax.X)
+	}
+	return minSize, maxSize
+}
+
+// Get the size of the left child of a split, moved as little as possible from the size its aspect
+// gives it so both children get at least their minimum and, if that's possible too, at most their maximum
+// If both minimums don't fit, the space is shared in proportion to them
+func (b *Branch) limitSplit(size, leftSize int, hints map[WindowID]SizeHints) int {
+	leftMin, leftMax := b.ChildLeft.sizeLimits(hints)
+	rightMin, rightMax := b.ChildRight.sizeLimits(hints)
+	minLeft, maxLeft := leftMin.X, leftMax.X
+	minRight, maxRight := rightMin.X, rightMax.X
+	if b.Direction == DirectionVertical {
+		minLeft, maxLeft = leftMin.Y, leftMax.Y
+		minRight, maxRight = rightMin.Y, rightMax.Y
+	}
+	if minLeft+minRight > size {
+		return size * minLeft / (minLeft + minRight)
+	}
+	low, high := minLeft, size-minRight
+	if maxRight > 0 {
+		low = max(low, min(size-maxRight, high))
+	}
+	if maxLeft > 0 {
+		high = min(high, max(maxLeft, low))
+	}
+	return min(max(leftSize, low), high)
+}
+
+// Shrink the areas of windows that got more space than their maximum size, keeping them centered in their area
+// Expects the caller to hold the lock
+func (t *Tree) applyMaxSizes(geometries []LeafGeometry) {
+	for i := range geometries {
+		hints, ok := t.hints[geometries[i].Leaf.Window]
+		if !ok {
+			continue
+		}
+		rect := &geometries[i].Area
+		if hints.Max.X > 0 && rect.Size.X > hints.Max.X {
+			rect.Position.X += (rect.Size.X - hints.Max.X) / 2
+			rect.Size.X = hints.Max.X
+		}
+		if hints.Max.Y > 0 && rect.Size.Y > hints.Max.Y {
+			rect.Position.Y += (rect.Size.Y - hints.Max.Y) / 2
+			rect.Size.Y = hints.Max.Y
+		}
+	}
+}
+
+// Get the larger of two maximums, where 0 means unlimited
+func largerLimit(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return max(a, b)
+}
+
+// Get the sum of two maximums, where 0 means unlimited
+func limitSum(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return a + b
+}
@@ -0,0 +1,61 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func TestSizeHintsMin(t *testing.T) {
+	tree, _ := threeAppTree()
+
+	// a needs more than its half, b and c next to it only care about their height
+	tree.SetSizeHints(1, SizeHints{Min: generaldata.Vector2i{X: 70}})
+	tree.SetSizeHints(2, SizeHints{Min: generaldata.Vector2i{Y: 80}})
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 70, 100),
+		"b": rect(70, 0, 30, 80),
+		"c": rect(70, 80, 30, 20),
+	})
+
+	// Minimums that don't fit share the space in proportion
+	tree.SetSizeHints(3, SizeHints{Min: generaldata.Vector2i{Y: 120}})
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 70, 100),
+		"b": rect(70, 0, 30, 40),
+		"c": rect(70, 40, 30, 60),
+	})
+
+	tree.SetSizeHints(1, SizeHints{})
+	tree.SetSizeHints(2, SizeHints{})
+	tree.SetSizeHints(3, SizeHints{})
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 50, 100),
+		"b": rect(50, 0, 50, 50),
+		"c": rect(50, 50, 50, 50),
+	})
+}
+
+func TestSizeHintsMax(t *testing.T) {
+	tree, _ := threeAppTree()
+
+	// a can't use its half, so the rest goes to b and c
+	tree.SetSizeHints(1, SizeHints{Max: generaldata.Vector2i{X: 30, Y: 60}})
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 20, 30, 60),
+		"b": rect(30, 0, 70, 50),
+		"c": rect(30, 50, 70, 50),
+	})
+	if hints := tree.SizeHints(1); hints.Max.X != 30 {
+		t.Errorf("Expected hints to be stored, got %+v", hints)
+	}
+
+	// Windows that can't be made large enough get centered in their share
+	tree.SetSizeHints(2, SizeHints{Max: generaldata.Vector2i{X: 50}})
+	tree.SetSizeHints(3, SizeHints{Max: generaldata.Vector2i{X: 50}})
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(10, 20, 30, 60),
+		"b": rect(50, 0, 50, 50),
+		"c": rect(50, 50, 50, 50),
+	})
+}
@@ -0,0 +1,145 @@
+package tiler
+
+import "slices"
+
+// Amount of changes that can be undone. Older ones are forgotten
+const HISTORY_SIZE = 50
+
+// Copy of the structure of a tree, used to undo and redo changes
+type snapshot struct {
+	root    Node
+	focused *Leaf // Copy of the last focused container inside root
+}
+
+// Remember the current structure of the tree, so that the next changes can be undone back to it
+// Tree operations do this themselves. Use this for changes made up of many operations,
+// like dragging an edge with the cursor, so all of them are undone at once
+func (t *Tree) Checkpoint() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.remember()
+}
+
+// Restore the structure from before the last change
+// Windows that got closed since then come back as placeholders waiting for a window of the same app,
+// windows that weren't in the tree back then are added like new windows
+// Returns false if there is nothing to undo
+func (t *Tree) Undo() bool {
+	t.beginChange()
+	defer t.endChange()
+
+	if len(t.undo) == 0 {
+		return false
+	}
+	t.redo = append(t.redo, t.snapshot())
+	t.restore(t.undo[len(t.undo)-1])
+	t.undo = t.undo[:len(t.undo)-1]
+	return true
+}
+
+// Apply the last undone change again
+// Returns false if there is nothing to redo
+func (t *Tree) Redo() bool {
+	t.beginChange()
+	defer t.endChange()
+
+	if len(t.redo) == 0 {
+		return false
+	}
+	t.undo = append(t.undo, t.snapshot())
+	t.restore(t.redo[len(t.redo)-1])
+	t.redo = t.redo[:len(t.redo)-1]
+	return true
+}
+
+// Push the current structure onto the undo history and forget everything that could be redone
+// Within a transaction only the structure from before its first change is remembered, so it is undone as a whole
+// Expects the caller to hold the lock
+func (t *Tree) remember() {
+	switch {
+	case t.transaction == nil:
+		t.undo = append(t.undo, t.snapshot())
+	case !t.transaction.remembered:
+		t.undo = append(t.undo, *t.transaction.before)
+		t.transaction.remembered = true
+	default:
+		return
+	}
+	if len(t.undo) > HISTORY_SIZE {
+		t.undo = t.undo[len(t.undo)-HISTORY_SIZE:]
+	}
+	t.redo = nil
+}
+
+// Copy the current structure of the tree
+// Expects the caller to hold the lock
+func (t *Tree) snapshot() snapshot {
+	s := snapshot{}
+	s.root = t.Root.clone(t.LastFocusedContainer, &s.focused)
+	return s
+}
+
+// Replace the structure of the tree with a snapshot
+// Expects the caller to hold the lock
+func (t *Tree) restore(s snapshot) {
+	kept := map[WindowID]bool{}
+	s.root.walkLeaves(func(leaf *Leaf) {
+		if leaf.IsEmpty {
+			return
+		}
+		current := t.leaves[leaf.Window]
+		if current == nil {
+			// Closed in the meantime, wait for the app to come back instead
+			leaf.Window = EMPTY_WINDOW_ID
+			leaf.IsEmpty = true
+			leaf.Marks = nil
+			leaf.swallowed = nil
+			return
+		}
+		// Marks and swallowed windows belong to the window, not to the layout, so they aren't undone
+		leaf.Marks = current.Marks
+		leaf.swallowed = current.swallowed
+		kept[leaf.Window] = true
+	})
+	added := []Leaf{}
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		if !leaf.IsEmpty && !kept[leaf.Window] {
+			added = append(added, *leaf)
+		}
+	})
+
+	t.Root = s.root
+	t.relink()
+	focused := s.focused
+	if focused == nil {
+		focused = t.Root.firstLeaf()
+	}
+	t.focusLeaf(focused)
+
+	for _, leaf := range added {
+		if !t.fillPlaceholder(leaf.Window, leaf.AppId, "") {
+			t.addApp(leaf.Window, leaf.AppId)
+		}
+	}
+	t.restoreMetadata(added)
+}
+
+// Deep copy a node
+// If the copied leaves include focused, its copy is stored in focusedCopy
+func (n *Node) clone(focused *Leaf, focusedCopy **Leaf) Node {
+	switch n.Type {
+	case NodeTypeLeaf:
+		leaf := *n.Leaf
+		leaf.Marks = slices.Clone(leaf.Marks)
+		if n.Leaf == focused {
+			*focusedCopy = &leaf
+		}
+		return Node{Type: NodeTypeLeaf, Leaf: &leaf}
+	default:
+		branch := *n.Branch
+		branch.ChildLeft = n.Branch.ChildLeft.clone(focused, focusedCopy)
+		branch.ChildRight = n.Branch.ChildRight.clone(focused, focusedCopy)
+		return Node{Type: NodeTypeBranch, Branch: &branch}
+	}
+}
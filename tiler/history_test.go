@@ -0,0 +1,92 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func TestUndoRedo(t *testing.T) {
+	tree, leaves := threeAppTree()
+
+	tree.Rotate(ScopeRoot, true)
+	if !tree.Undo() {
+		t.Fatalf("Nothing to undo after rotating")
+	}
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 50, 100),
+		"b": rect(50, 0, 50, 50),
+		"c": rect(50, 50, 50, 50),
+	})
+
+	if !tree.Redo() {
+		t.Fatalf("Nothing to redo after undoing")
+	}
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 100, 50),
+		"b": rect(50, 50, 50, 50),
+		"c": rect(0, 50, 50, 50),
+	})
+	if tree.Redo() {
+		t.Errorf("Redid a change twice")
+	}
+
+	// Focus changes aren't part of the history
+	tree.FocusApp(leaves["b"].Window)
+	tree.Undo()
+	if tree.Undo() {
+		t.Errorf("Expected only the rotation in the history")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Invalid tree after undoing: %s", err)
+	}
+}
+
+func TestUndoRemoval(t *testing.T) {
+	tree, _ := threeAppTree()
+
+	tree.RemoveApp(2, true)
+	tree.Undo()
+	if err := tree.Validate(); err != nil {
+		t.Fatalf("Invalid tree after undoing: %s", err)
+	}
+	// The closed window left a placeholder in its old spot
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 50, 100),
+		"c": rect(50, 50, 50, 50),
+	})
+	if !tree.FillPlaceholder(4, "b", "") {
+		t.Fatalf("No placeholder for the closed app")
+	}
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 50, 100),
+		"b": rect(50, 0, 50, 50),
+		"c": rect(50, 50, 50, 50),
+	})
+}
+
+func TestUndoKeepsNewWindows(t *testing.T) {
+	tree, _ := threeAppTree()
+
+	tree.ToggleSplit()
+	tree.AddApp(4, "d")
+	tree.Undo()
+	if err := tree.Validate(); err != nil {
+		t.Fatalf("Invalid tree after undoing: %s", err)
+	}
+	if tree.FindApp(4) == nil {
+		t.Errorf("Window opened after the change got lost")
+	}
+
+	single := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	for i := 0; i < HISTORY_SIZE+10; i++ {
+		single.Checkpoint()
+	}
+	undone := 0
+	for single.Undo() {
+		undone++
+	}
+	if undone != HISTORY_SIZE {
+		t.Errorf("Expected history to be limited to %d, got %d", HISTORY_SIZE, undone)
+	}
+}
@@ -0,0 +1,216 @@
+package tiler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Serialisable form of a tree, used to save layouts to files and restore them later
+// A node with both children set is a branch, a node without any children is a leaf
+type LayoutNode struct {
+	Direction  string      `json:"direction,omitempty" toml:"direction,omitempty" yaml:"direction,omitempty"`       // Either "vertical" or "horizontal". Only used by branches
+	AspectLeft int         `json:"aspect_left,omitempty" toml:"aspect_left,omitempty" yaml:"aspect_left,omitempty"` // Percentage the left child has of the space, from 1 to 99. Half of it if left out. Only used by branches
+	Left       *LayoutNode `json:"left,omitempty" toml:"left,omitempty" yaml:"left,omitempty"`                      // Top child if split vertically
+	Right      *LayoutNode `json:"right,omitempty" toml:"right,omitempty" yaml:"right,omitempty"`                   // Bottom child if split vertically
+	AppId      string      `json:"app_id,omitempty" toml:"app_id,omitempty" yaml:"app_id,omitempty"`                // App expected in this leaf. Only used by leaves
+	Title      string      `json:"title,omitempty" toml:"title,omitempty" yaml:"title,omitempty"`                   // Regular expression the title of the expected window has to match. Only used by leaves
+	Mode       string      `json:"mode,omitempty" toml:"mode,omitempty" yaml:"mode,omitempty"`                      // Either "tabbed" or "stacked". Split if empty. Only used by branches
+}
+
+// Share of the left child of branches in saved layouts that don't set one
+const DEFAULT_LAYOUT_ASPECT = 50
+
+var directionNames = map[Direction]string{
+	DirectionVertical:   "vertical",
+	DirectionHorizontal: "horizontal",
+}
+
+var containerModeNames = map[ContainerMode]string{
+	ContainerModeSplit:   "",
+	ContainerModeTabbed:  "tabbed",
+	ContainerModeStacked: "stacked",
+}
+
+// Export the structure of the tree
+// Leaves only keep the app ID of their window since window IDs don't survive a restart
+func (t *Tree) ExportLayout() LayoutNode {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return exportNode(&t.Root)
+}
+
+func exportNode(n *Node) LayoutNode {
+	if n.Type == NodeTypeLeaf {
+		layout := LayoutNode{AppId: n.Leaf.AppId}
+		if n.Leaf.Title != nil {
+			layout.Title = n.Leaf.Title.String()
+		}
+		return layout
+	}
+	left := exportNode(&n.Branch.ChildLeft)
+	right := exportNode(&n.Branch.ChildRight)
+	return LayoutNode{
+		Direction:  directionNames[n.Branch.Direction],
+		AspectLeft: n.Branch.AspectLeft,
+		Mode:       containerModeNames[n.Branch.Mode],
+		Left:       &left,
+		Right:      &right,
+	}
+}
+
+// Replace the structure of the tree with a saved layout
+// Every leaf of the layout starts out as an empty placeholder waiting for a window with its app ID and title
+// Windows that were already in the tree get put into a placeholder matching their app ID or are added like new windows.
+// Their titles aren't known to the tree, so they never take placeholders waiting for a specific title
+func (t *Tree) ImportLayout(layout LayoutNode) error {
+	root, err := importNode(&layout)
+	if err != nil {
+		return err
+	}
+
+	t.beginChange()
+	defer t.endChange()
+
+	t.remember()
+	previous := []Leaf{}
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		if !leaf.IsEmpty {
+			previous = append(previous, *leaf)
+		}
+	})
+
+	t.Root = root
+	t.LastFocusedContainer = root.firstLeaf()
+	t.relink()
+
+	for _, leaf := range previous {
+		if !t.fillPlaceholder(leaf.Window, leaf.AppId, "") {
+			t.addApp(leaf.Window, leaf.AppId)
+		}
+	}
+	t.restoreMetadata(previous)
+	return nil
+}
+
+// Add a saved layout to the tree, like a template waiting to be filled with new windows
+// An empty last focused container gets replaced by the layout, otherwise it gets split to make space for it
+// The windows already in the tree stay where they are
+func (t *Tree) AppendLayout(layout LayoutNode) error {
+	node, err := importNode(&layout)
+	if err != nil {
+		return err
+	}
+
+	t.beginChange()
+	defer t.endChange()
+
+	t.remember()
+	target := t.LastFocusedContainer
+	if !target.IsEmpty || target.IsPlaceholder() {
+		target = t.SplitLastFocusedContainer()
+	}
+	t.replaceChild(target.parent, Node{Type: NodeTypeLeaf, Leaf: target}, node)
+	node.relink(t.leaves)
+	t.focusLeaf(node.firstLeaf())
+	return nil
+}
+
+func importNode(layout *LayoutNode) (Node, error) {
+	if layout.Left == nil && layout.Right == nil {
+		var title *regexp.Regexp
+		if layout.Title != "" {
+			var err error
+			if title, err = regexp.Compile(layout.Title); err != nil {
+				return Node{}, fmt.Errorf("invalid title: %w", err)
+			}
+		}
+		return Node{
+			Type: NodeTypeLeaf,
+			Leaf: &Leaf{
+				Window:  EMPTY_WINDOW_ID,
+				AppId:   layout.AppId,
+				IsEmpty: true,
+				Title:   title,
+			},
+		}, nil
+	}
+	if layout.Left == nil || layout.Right == nil {
+		return Node{}, errors.New("branch with only one child")
+	}
+
+	branch := Branch{AspectLeft: layout.AspectLeft}
+	if branch.AspectLeft == 0 {
+		branch.AspectLeft = DEFAULT_LAYOUT_ASPECT
+	} else if branch.AspectLeft < MIN_ASPECT || branch.AspectLeft > MAX_ASPECT {
+		return Node{}, fmt.Errorf("aspect %d out of range, expected %d to %d", branch.AspectLeft, MIN_ASPECT, MAX_ASPECT)
+	}
+	switch layout.Direction {
+	case directionNames[DirectionVertical]:
+		branch.Direction = DirectionVertical
+	case directionNames[DirectionHorizontal]:
+		branch.Direction = DirectionHorizontal
+	default:
+		return Node{}, fmt.Errorf("unknown direction \"%s\"", layout.Direction)
+	}
+	switch layout.Mode {
+	case containerModeNames[ContainerModeSplit], "split":
+		branch.Mode = ContainerModeSplit
+	case containerModeNames[ContainerModeTabbed]:
+		branch.Mode = ContainerModeTabbed
+	case containerModeNames[ContainerModeStacked]:
+		branch.Mode = ContainerModeStacked
+	default:
+		return Node{}, fmt.Errorf("unknown container mode \"%s\"", layout.Mode)
+	}
+	left, err := importNode(layout.Left)
+	if err != nil {
+		return Node{}, fmt.Errorf("left child: %w", err)
+	}
+	right, err := importNode(layout.Right)
+	if err != nil {
+		return Node{}, fmt.Errorf("right child: %w", err)
+	}
+	branch.ChildLeft = left
+	branch.ChildRight = right
+	return Node{Type: NodeTypeBranch, Branch: &branch}, nil
+}
+
+// Put a window into the first placeholder it matches, in left to right order
+// Returns false if there is no such placeholder
+func (t *Tree) FillPlaceholder(window WindowID, appId, title string) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	return t.fillPlaceholder(window, appId, title)
+}
+
+// Same as FillPlaceholder, but expects the caller to already hold the lock
+func (t *Tree) fillPlaceholder(window WindowID, appId, title string) bool {
+	var placeholder *Leaf
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		if placeholder == nil && leaf.IsPlaceholder() && leaf.matches(appId, title) {
+			placeholder = leaf
+		}
+	})
+	if placeholder == nil {
+		return false
+	}
+	t.fillLeaf(placeholder, window, appId)
+	return true
+}
+
+// Check if this leaf is empty, but waiting for a window of a specific app or with a specific title
+func (l *Leaf) IsPlaceholder() bool {
+	return l.IsEmpty && (l.AppId != "" || l.Title != nil)
+}
+
+// Check if a window fits the criteria of this placeholder
+// Criteria that aren't set match every window
+func (l *Leaf) matches(appId, title string) bool {
+	if l.AppId != "" && l.AppId != appId {
+		return false
+	}
+	return l.Title == nil || l.Title.MatchString(title)
+}
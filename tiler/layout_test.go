@@ -0,0 +1,157 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// A restored layout has the same geometry, with placeholders waiting for the apps
+func TestLayoutRoundTrip(t *testing.T) {
+	resolution := generaldata.Vector2i{X: 1920, Y: 1080}
+	original := NewTree(resolution)
+	original.AddApp(1, "editor")
+	original.AddApp(2, "terminal")
+	original.AddApp(3, "browser")
+	original.LastFocusedParent.AspectLeft = 30
+	original.LastFocusedParent.Mode = ContainerModeTabbed
+	expected := map[string]generaldata.Rect{}
+	for _, geometry := range original.Arrange() {
+		expected[geometry.Leaf.AppId] = geometry.Area
+	}
+
+	restored := NewTree(resolution)
+	if err := restored.ImportLayout(original.ExportLayout()); err != nil {
+		t.Fatalf("Failed to import layout: %s", err)
+	}
+	if geometries := restored.Arrange(); len(geometries) != 0 {
+		t.Errorf("Expected only placeholders, got %+v", geometries)
+	}
+	if restored.FillPlaceholder(10, "unknown", "") {
+		t.Errorf("Filled a placeholder for an unknown app")
+	}
+	for i, appId := range []string{"browser", "editor", "terminal"} {
+		if !restored.FillPlaceholder(WindowID(10+i), appId, "") {
+			t.Errorf("No placeholder for %s", appId)
+		}
+	}
+
+	geometries := restored.Arrange()
+	if len(geometries) != len(expected) {
+		t.Fatalf("Expected %d windows, got %+v", len(expected), geometries)
+	}
+	for _, geometry := range geometries {
+		if geometry.Area != expected[geometry.Leaf.AppId] {
+			t.Errorf("%s: expected %+v, got %+v", geometry.Leaf.AppId, expected[geometry.Leaf.AppId], geometry.Area)
+		}
+	}
+	if err := checkNode(&restored.Root, nil); err != nil {
+		t.Errorf("Invalid tree structure: %s", err)
+	}
+}
+
+// Windows already in the tree must survive importing a layout
+func TestLayoutImportKeepsWindows(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.AddApp(1, "terminal")
+	tree.AddApp(2, "browser")
+
+	layout := LayoutNode{
+		Direction:  "horizontal",
+		AspectLeft: 50,
+		Left:       &LayoutNode{AppId: "editor"},
+		Right:      &LayoutNode{AppId: "terminal"},
+	}
+	if err := tree.ImportLayout(layout); err != nil {
+		t.Fatalf("Failed to import layout: %s", err)
+	}
+	if leaf := tree.FindApp(1); leaf != tree.Root.Branch.ChildRight.firstLeaf() {
+		t.Errorf("Terminal not put into its placeholder")
+	}
+	if tree.FindApp(2) == nil {
+		t.Errorf("Browser got lost")
+	}
+	if !tree.Root.Branch.ChildLeft.firstLeaf().IsPlaceholder() {
+		t.Errorf("Editor placeholder got taken over")
+	}
+}
+
+func TestLayoutImportInvalid(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	invalid := []LayoutNode{
+		{Direction: "diagonal", Left: &LayoutNode{}, Right: &LayoutNode{}},
+		{Direction: "vertical", Left: &LayoutNode{}},
+		{Direction: "vertical", Mode: "floating", Left: &LayoutNode{}, Right: &LayoutNode{}},
+		{Direction: "vertical", AspectLeft: 300, Left: &LayoutNode{}, Right: &LayoutNode{}},
+		{Direction: "vertical", AspectLeft: -5, Left: &LayoutNode{}, Right: &LayoutNode{}},
+	}
+	for _, layout := range invalid {
+		if err := tree.ImportLayout(layout); err == nil {
+			t.Errorf("Imported invalid layout %+v", layout)
+		}
+	}
+}
+
+// Hand-written layouts may leave out the aspect, splitting the space in half
+func TestLayoutImportDefaultAspect(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	layout := LayoutNode{Direction: "horizontal", Left: &LayoutNode{AppId: "a"}, Right: &LayoutNode{AppId: "b"}}
+	if err := tree.ImportLayout(layout); err != nil {
+		t.Fatalf("Failed to import layout without aspect: %s", err)
+	}
+	tree.FillPlaceholder(1, "a", "")
+	tree.FillPlaceholder(2, "b", "")
+	geometries := tree.Arrange()
+	if len(geometries) != 2 {
+		t.Fatalf("Expected both placeholders to be filled, got %+v", geometries)
+	}
+	for _, geometry := range geometries {
+		if geometry.Area.Size != (generaldata.Vector2i{X: 50, Y: 100}) {
+			t.Errorf("%s: expected half of the space, got %+v", geometry.Leaf.AppId, geometry.Area)
+		}
+	}
+	if err := checkNode(&tree.Root, nil); err != nil {
+		t.Errorf("Invalid tree structure: %s", err)
+	}
+}
+
+// Placeholders of an appended template only take windows matching all of their criteria
+func TestLayoutAppendTemplate(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	template := LayoutNode{
+		Direction:  "horizontal",
+		AspectLeft: 50,
+		Left:       &LayoutNode{AppId: "terminal", Title: "^build"},
+		Right:      &LayoutNode{AppId: "terminal"},
+	}
+	if err := tree.AppendLayout(template); err != nil {
+		t.Fatalf("Failed to append layout: %s", err)
+	}
+	if !tree.FillPlaceholder(1, "terminal", "shell") || tree.FindApp(1) != tree.Root.Branch.ChildRight.Leaf {
+		t.Errorf("Terminal without matching title didn't take the right placeholder")
+	}
+	if tree.FillPlaceholder(2, "terminal", "shell") {
+		t.Errorf("Terminal filled a placeholder with a title it doesn't match")
+	}
+	if !tree.FillPlaceholder(3, "terminal", "build: make") || tree.FindApp(3) != tree.Root.Branch.ChildLeft.Leaf {
+		t.Errorf("Terminal with matching title didn't take the left placeholder")
+	}
+
+	// With windows around, the template gets its own space next to the focused one
+	if err := tree.AppendLayout(LayoutNode{Title: "notes"}); err != nil {
+		t.Fatalf("Failed to append layout: %s", err)
+	}
+	if !tree.LastFocusedContainer.IsPlaceholder() || tree.FindApp(3) == nil {
+		t.Errorf("Expected the template next to the focused window")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Invalid tree: %s", err)
+	}
+	if exported := tree.ExportLayout(); exported.Left.Left.Title != "" || exported.Left.Right.Title != "notes" {
+		t.Errorf("Expected only the open placeholder to keep its title, got %+v", exported.Left)
+	}
+
+	if err := tree.AppendLayout(LayoutNode{Title: "("}); err == nil {
+		t.Errorf("Appended a layout with an invalid title")
+	}
+}
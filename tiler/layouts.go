@@ -0,0 +1,234 @@
+package tiler
+
+import (
+	"fmt"
+	"math"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Name of the manual layout given by the structure of the tree itself
+const TREE_LAYOUT_NAME = "tree"
+
+// Defaults for new master-stack layouts
+const (
+	DEFAULT_MASTER_COUNT = 1
+	DEFAULT_MASTER_RATIO = 55
+)
+
+// An automatic layout algorithm
+// Instead of using the structure of the tree, it places the windows based only on their order
+type Layout interface {
+	// Name the layout can be selected with
+	Name() string
+	// Place the windows, in order, within the area
+	// Must return one geometry per window, in the same order
+	Arrange(windows []WindowID, area generaldata.Rect) []WindowGeometry
+}
+
+// Area an automatic layout assigned to a window
+type WindowGeometry struct {
+	Window WindowID
+	Area   generaldata.Rect
+}
+
+// One big master area on the left and a stack of the remaining windows on the right
+type MasterStack struct {
+	MasterCount int // Amount of windows sharing the master area
+	MasterRatio int // Percentage of the width the master area takes up if there is a stack
+}
+
+// Every window takes up the whole area, the focused one is shown on top
+type Monocle struct{}
+
+// Windows are placed in rows of equal height, filling them from the top left
+type Grid struct{}
+
+// Every window takes half of the space left over by the windows before it, alternating the direction
+// If Spiral is set, the leftover space rotates around the center instead of always being on the right or bottom
+type Dwindle struct {
+	Spiral bool
+}
+
+// Create an automatic layout by its name
+// The tree layout returns nil, since it's not an automatic layout
+func NewLayout(name string) (Layout, error) {
+	switch name {
+	case TREE_LAYOUT_NAME:
+		return nil, nil
+	case "master-stack":
+		return &MasterStack{MasterCount: DEFAULT_MASTER_COUNT, MasterRatio: DEFAULT_MASTER_RATIO}, nil
+	case "monocle":
+		return &Monocle{}, nil
+	case "grid":
+		return &Grid{}, nil
+	case "dwindle":
+		return &Dwindle{}, nil
+	case "spiral":
+		return &Dwindle{Spiral: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown layout \"%s\", expected one of tree, master-stack, monocle, grid, dwindle, spiral", name)
+	}
+}
+
+// Switch to an automatic layout, or back to the structure of the tree if nil
+// The tree keeps its structure either way, so switching back restores the previous arrangement
+func (t *Tree) SetLayout(layout Layout) {
+	t.beginChange()
+	defer t.endChange()
+
+	t.layout = layout
+}
+
+// Get the name of the layout currently in use
+func (t *Tree) LayoutName() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.layout == nil {
+		return TREE_LAYOUT_NAME
+	}
+	return t.layout.Name()
+}
+
+// Change the amount of master windows and the percentage of the master area by the given amounts
+// Returns false if the tree isn't using a master-stack layout
+func (t *Tree) AdjustMaster(countDelta, ratioDelta int) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	masterStack, ok := t.layout.(*MasterStack)
+	if !ok {
+		return false
+	}
+	masterStack.MasterCount = max(masterStack.MasterCount+countDelta, 0)
+	masterStack.MasterRatio = min(max(masterStack.MasterRatio+ratioDelta, MIN_ASPECT), MAX_ASPECT)
+	t.emit(Event{Type: EventRatioChanged})
+	return true
+}
+
+func (m *MasterStack) Name() string {
+	return "master-stack"
+}
+
+func (m *MasterStack) Arrange(windows []WindowID, area generaldata.Rect) []WindowGeometry {
+	masterCount := min(max(m.MasterCount, 0), len(windows))
+	if masterCount == 0 || masterCount == len(windows) {
+		// Only one of the two areas is in use, so it gets all the space
+		return arrangeColumn(windows, area)
+	}
+	ratio := min(max(m.MasterRatio, MIN_ASPECT), MAX_ASPECT)
+	masterArea, stackArea := splitSpan(area, DirectionHorizontal, area.Size.X*ratio/100)
+	return append(arrangeColumn(windows[:masterCount], masterArea), arrangeColumn(windows[masterCount:], stackArea)...)
+}
+
+func (m *Monocle) Name() string {
+	return "monocle"
+}
+
+func (m *Monocle) Arrange(windows []WindowID, area generaldata.Rect) []WindowGeometry {
+	geometries := make([]WindowGeometry, len(windows))
+	for i, window := range windows {
+		geometries[i] = WindowGeometry{Window: window, Area: area}
+	}
+	return geometries
+}
+
+func (g *Grid) Name() string {
+	return "grid"
+}
+
+func (g *Grid) Arrange(windows []WindowID, area generaldata.Rect) []WindowGeometry {
+	if len(windows) == 0 {
+		return []WindowGeometry{}
+	}
+	columns := int(math.Ceil(math.Sqrt(float64(len(windows)))))
+	rows := (len(windows) + columns - 1) / columns
+	geometries := []WindowGeometry{}
+	for row := 0; row < rows; row++ {
+		rowArea := spanPart(area, DirectionVertical, row, rows)
+		// The last row may have fewer windows, which then get wider
+		rowWindows := windows[row*columns : min((row+1)*columns, len(windows))]
+		for column, window := range rowWindows {
+			geometries = append(geometries, WindowGeometry{
+				Window: window,
+				Area:   spanPart(rowArea, DirectionHorizontal, column, len(rowWindows)),
+			})
+		}
+	}
+	return geometries
+}
+
+func (d *Dwindle) Name() string {
+	if d.Spiral {
+		return "spiral"
+	}
+	return "dwindle"
+}
+
+func (d *Dwindle) Arrange(windows []WindowID, area generaldata.Rect) []WindowGeometry {
+	geometries := []WindowGeometry{}
+	remaining := area
+	for i, window := range windows {
+		if i == len(windows)-1 {
+			geometries = append(geometries, WindowGeometry{Window: window, Area: remaining})
+			break
+		}
+		direction := DirectionHorizontal
+		if i%2 == 1 {
+			direction = DirectionVertical
+		}
+		size := sizeAlong(remaining, direction) / 2
+		own, rest := splitSpan(remaining, direction, size)
+		// A spiral puts every other pair of windows on the far side, so the leftover space turns around the center
+		if d.Spiral && i%4 >= 2 {
+			rest, own = splitSpan(remaining, direction, sizeAlong(remaining, direction)-size)
+		}
+		geometries = append(geometries, WindowGeometry{Window: window, Area: own})
+		remaining = rest
+	}
+	return geometries
+}
+
+// Stack windows on top of each other, sharing the height equally
+func arrangeColumn(windows []WindowID, area generaldata.Rect) []WindowGeometry {
+	geometries := make([]WindowGeometry, len(windows))
+	for i, window := range windows {
+		geometries[i] = WindowGeometry{Window: window, Area: spanPart(area, DirectionVertical, i, len(windows))}
+	}
+	return geometries
+}
+
+// Get the size of an area along the axis of a direction
+func sizeAlong(area generaldata.Rect, direction Direction) int {
+	if direction == DirectionVertical {
+		return area.Size.Y
+	}
+	return area.Size.X
+}
+
+// Cut an area in two along the axis of a direction, the first part being size long
+func splitSpan(area generaldata.Rect, direction Direction, size int) (generaldata.Rect, generaldata.Rect) {
+	first, second := area, area
+	if direction == DirectionVertical {
+		first.Size.Y = size
+		second.Position.Y += size
+		second.Size.Y -= size
+	} else {
+		first.Size.X = size
+		second.Position.X += size
+		second.Size.X -= size
+	}
+	return first, second
+}
+
+// Get the index-th of count equal parts of an area along the axis of a direction
+// Parts start at whole pixels, so together they always cover the full area
+func spanPart(area generaldata.Rect, direction Direction, index, count int) generaldata.Rect {
+	length := sizeAlong(area, direction)
+	start := length * index / count
+	end := length * (index + 1) / count
+	part, _ := splitSpan(area, direction, end)
+	_, part = splitSpan(part, direction, start)
+	return part
+}
@@ -0,0 +1,139 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func checkLayout(t *testing.T, layout Layout, area generaldata.Rect, expected []generaldata.Rect) {
+	t.Helper()
+	windows := []WindowID{}
+	for i := range expected {
+		windows = append(windows, WindowID(i+1))
+	}
+	geometries := layout.Arrange(windows, area)
+	if len(geometries) != len(expected) {
+		t.Fatalf("%s: expected %d geometries, got %+v", layout.Name(), len(expected), geometries)
+	}
+	for i, geometry := range geometries {
+		if geometry.Window != windows[i] || geometry.Area != expected[i] {
+			t.Errorf("%s: expected window %d at %+v, got %+v", layout.Name(), windows[i], expected[i], geometry)
+		}
+	}
+}
+
+func TestLayoutMasterStack(t *testing.T) {
+	layout := &MasterStack{MasterCount: 1, MasterRatio: 60}
+	area := rect(0, 0, 100, 90)
+	checkLayout(t, layout, area, []generaldata.Rect{area})
+	checkLayout(t, layout, area, []generaldata.Rect{
+		rect(0, 0, 60, 90),
+		rect(60, 0, 40, 45),
+		rect(60, 45, 40, 45),
+	})
+
+	layout.MasterCount = 2
+	checkLayout(t, layout, area, []generaldata.Rect{
+		rect(0, 0, 60, 45),
+		rect(0, 45, 60, 45),
+		rect(60, 0, 40, 90),
+	})
+}
+
+func TestLayoutGrid(t *testing.T) {
+	checkLayout(t, &Grid{}, rect(0, 0, 90, 100), []generaldata.Rect{
+		rect(0, 0, 30, 50),
+		rect(30, 0, 30, 50),
+		rect(60, 0, 30, 50),
+		rect(0, 50, 45, 50),
+		rect(45, 50, 45, 50),
+	})
+}
+
+func TestLayoutDwindle(t *testing.T) {
+	area := rect(0, 0, 100, 100)
+	checkLayout(t, &Dwindle{}, area, []generaldata.Rect{
+		rect(0, 0, 50, 100),
+		rect(50, 0, 50, 50),
+		rect(50, 50, 25, 50),
+		rect(75, 50, 25, 50),
+	})
+	checkLayout(t, &Dwindle{Spiral: true}, area, []generaldata.Rect{
+		rect(0, 0, 50, 100),
+		rect(50, 0, 50, 50),
+		rect(75, 50, 25, 50),
+		rect(50, 75, 25, 25),
+		rect(50, 50, 25, 25),
+	})
+}
+
+func TestLayoutMonocle(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.AddApp(1, "a")
+	tree.AddApp(2, "b")
+	tree.AddApp(3, "c")
+	tree.SetLayout(&Monocle{})
+
+	for _, geometry := range tree.Arrange() {
+		if geometry.Area != rect(0, 0, 100, 100) {
+			t.Errorf("%s: expected the full area, got %+v", geometry.Leaf.AppId, geometry.Area)
+		}
+	}
+	// Monocle windows are cycled like tabs
+	for _, expected := range []string{"a", "b"} {
+		if leaf := tree.CycleTab(true); leaf.AppId != expected {
+			t.Errorf("Expected %s as next window, got %+v", expected, leaf)
+		}
+	}
+}
+
+func TestTreeLayoutSwitch(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.AddApp(1, "a")
+	tree.AddApp(2, "b")
+	tree.AddApp(3, "c")
+	before := tree.Arrange()
+
+	layout, err := NewLayout("master-stack")
+	if err != nil {
+		t.Fatalf("Failed to create master-stack layout: %s", err)
+	}
+	tree.SetLayout(layout)
+	if name := tree.LayoutName(); name != "master-stack" {
+		t.Errorf("Expected master-stack layout, got %s", name)
+	}
+	if appIds := arrangedAppIds(&tree); appIds[0] != "a" || appIds[1] != "b" || appIds[2] != "c" {
+		t.Errorf("Windows not arranged in tree order: %v", appIds)
+	}
+	if tree.ResizeEdge(1, SideRight, 10) {
+		t.Errorf("Resized a split of the tree while using an automatic layout")
+	}
+	if !tree.AdjustMaster(1, 5) {
+		t.Errorf("Failed to adjust master-stack layout")
+	}
+	if area := tree.Arrange()[0].Area; area != rect(0, 0, 60, 50) {
+		t.Errorf("Expected first master at %+v, got %+v", rect(0, 0, 60, 50), area)
+	}
+
+	// Neighbours follow the automatic layout
+	if right := tree.FindNeighbours(tree.FindApp(2)).Right; right != tree.FindApp(3) {
+		t.Errorf("Expected c right of b, got %+v", right)
+	}
+
+	layout, _ = NewLayout(TREE_LAYOUT_NAME)
+	tree.SetLayout(layout)
+	if tree.AdjustMaster(1, 0) {
+		t.Errorf("Adjusted master of the tree layout")
+	}
+	after := tree.Arrange()
+	for i := range before {
+		if before[i] != after[i] {
+			t.Errorf("Tree arrangement not restored, expected %+v, got %+v", before[i], after[i])
+		}
+	}
+
+	if _, err := NewLayout("floating"); err == nil {
+		t.Errorf("Created unknown layout")
+	}
+}
@@ -0,0 +1,140 @@
+package tiler
+
+import "slices"
+
+// Give a window a mark, so it can be found again by name
+// A mark belongs to a single window, so it is taken away from whichever window had it before
+// Returns false if the window isn't in the tree
+func (t *Tree) Mark(window WindowID, mark string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.mark(window, mark)
+}
+
+// Same as Mark, but expects the caller to already hold the lock
+func (t *Tree) mark(window WindowID, mark string) bool {
+	leaf := t.findApp(window)
+	if leaf == nil {
+		return false
+	}
+	if slices.Contains(leaf.Marks, mark) {
+		return true
+	}
+	t.unmark(mark)
+	leaf.Marks = append(leaf.Marks, mark)
+	return true
+}
+
+// Remove a mark from whichever window has it
+// Returns false if no window has that mark
+func (t *Tree) Unmark(mark string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.unmark(mark)
+}
+
+// Same as Unmark, but expects the caller to already hold the lock
+func (t *Tree) unmark(mark string) bool {
+	leaf := t.findMark(mark)
+	if leaf == nil {
+		return false
+	}
+	leaf.Marks = slices.DeleteFunc(leaf.Marks, func(other string) bool {
+		return other == mark
+	})
+	if len(leaf.Marks) == 0 {
+		leaf.Marks = nil
+	}
+	return true
+}
+
+// Get the marks of a window, in the order they were added
+// Returns nil if the window isn't in the tree or has no marks
+func (t *Tree) MarksOf(window WindowID) []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	leaf := t.findApp(window)
+	if leaf == nil {
+		return nil
+	}
+	return slices.Clone(leaf.Marks)
+}
+
+// Find the leaf of the window with the given mark
+// Returns nil if no window has that mark
+func (t *Tree) FindMark(mark string) *Leaf {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.findMark(mark)
+}
+
+// Same as FindMark, but expects the caller to already hold the lock
+func (t *Tree) findMark(mark string) *Leaf {
+	for _, leaf := range t.leaves {
+		if slices.Contains(leaf.Marks, mark) {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// Focus the window with the given mark
+// Returns the newly focused leaf or nil if no window has that mark
+func (t *Tree) FocusMark(mark string) *Leaf {
+	t.beginChange()
+	defer t.endChange()
+
+	leaf := t.findMark(mark)
+	if leaf == nil {
+		return nil
+	}
+	t.focusLeaf(leaf)
+	return leaf
+}
+
+// Swap the last focused container with the window with the given mark
+// The focus follows the moved app
+// Returns false if no other window has that mark
+func (t *Tree) SwapMark(mark string) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	leaf := t.LastFocusedContainer
+	target := t.findMark(mark)
+	if leaf == nil || target == nil || target == leaf {
+		return false
+	}
+	t.remember()
+	t.swapLeaves(leaf, target)
+	return true
+}
+
+// Move the last focused container into the container of the window with the given mark
+// The marked window gets split and the moved container is placed after it. Inside a tabbed or stacked
+// container, the moved container becomes a new tab next to the marked window
+// Returns false if no other window has that mark
+func (t *Tree) MoveToMark(mark string) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	leaf := t.LastFocusedContainer
+	target := t.findMark(mark)
+	if leaf == nil || target == nil || target == leaf {
+		return false
+	}
+	t.remember()
+
+	direction := DirectionVertical
+	mode := ContainerModeSplit
+	if target.parent != nil {
+		direction = target.parent.Direction
+		mode = target.parent.Mode
+	}
+	t.moveNextTo(leaf, target, direction, mode, false)
+	t.focusLeaf(leaf)
+	return true
+}
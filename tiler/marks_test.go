@@ -0,0 +1,83 @@
+package tiler
+
+import (
+	"slices"
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func TestMarks(t *testing.T) {
+	tree, leaves := threeAppTree()
+
+	tree.Mark(1, "main")
+	tree.Mark(1, "code")
+	tree.Mark(2, "main")
+	if marks := tree.MarksOf(1); !slices.Equal(marks, []string{"code"}) {
+		t.Errorf("Expected the mark to move to the other window, got %v", marks)
+	}
+	if tree.FindMark("main") != leaves["b"] {
+		t.Errorf("Mark not found on b")
+	}
+	if tree.Mark(7, "missing") {
+		t.Errorf("Marked a window that isn't in the tree")
+	}
+
+	if tree.FocusMark("main") != leaves["b"] || tree.LastFocusedContainer != leaves["b"] {
+		t.Errorf("Focusing the mark didn't focus b")
+	}
+
+	// Marks stick to their window when it moves
+	tree.FocusApp(3)
+	if !tree.SwapMark("code") {
+		t.Fatalf("Failed to swap with mark")
+	}
+	if tree.FindMark("code").Window != 1 || tree.FindApp(1) != leaves["c"] {
+		t.Errorf("Mark didn't follow its window")
+	}
+
+	// Closed windows lose their marks
+	tree.RemoveApp(1, true)
+	if tree.Unmark("code") {
+		t.Errorf("Mark of a closed window is still around")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Invalid tree: %s", err)
+	}
+}
+
+func TestMoveToMark(t *testing.T) {
+	tree, _ := threeAppTree()
+	tree.Mark(2, "target")
+	tree.FocusApp(1)
+
+	if !tree.MoveToMark("target") {
+		t.Fatalf("Failed to move to mark")
+	}
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 25, 100, 25),
+		"b": rect(0, 0, 100, 25),
+		"c": rect(0, 50, 100, 50),
+	})
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Invalid tree: %s", err)
+	}
+	if tree.LastFocusedContainer.Window != 1 {
+		t.Errorf("Focus didn't follow the moved window")
+	}
+
+	// Inside a tabbed container the moved window becomes another tab
+	tabbed, _ := threeAppTree()
+	tabbed.FocusApp(2)
+	tabbed.SetContainerMode(ContainerModeTabbed)
+	tabbed.Mark(2, "tabs")
+	tabbed.FocusApp(1)
+	tabbed.MoveToMark("tabs")
+	if appIds := visibleAppIds(tabbed); !slices.Equal(appIds, []string{"a"}) {
+		t.Errorf("Expected only the moved tab to be visible, got %v", appIds)
+	}
+	tabbed.CycleTab(true)
+	if appIds := visibleAppIds(tabbed); !slices.Equal(appIds, []string{"c"}) {
+		t.Errorf("Expected the moved window to be a tab next to b, got %v", appIds)
+	}
+}
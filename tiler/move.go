@@ -0,0 +1,97 @@
+package tiler
+
+// Swap the last focused container with its neighbour on the given side
+// The focus follows the moved app
+// Returns false if there is no neighbour on that side
+func (t *Tree) SwapSide(side Side) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	return t.swapSide(side)
+}
+
+// Same as SwapSide, but expects the caller to already hold the lock
+func (t *Tree) swapSide(side Side) bool {
+	leaf := t.LastFocusedContainer
+	target := t.findNeighbours(leaf).OnSide(side)
+	if leaf == nil || target == nil {
+		return false
+	}
+	t.remember()
+	t.swapLeaves(leaf, target)
+	return true
+}
+
+// Move the last focused container one step towards the given side
+// If the neighbour on that side shares the same parent, the two get swapped
+// Otherwise the container is taken out of its current split and the neighbour gets split to make space for it,
+// with the moved container placed on the side facing where it came from
+// Returns false if there is no neighbour on that side
+func (t *Tree) MoveSide(side Side) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	return t.moveSide(side)
+}
+
+// Same as MoveSide, but expects the caller to already hold the lock
+func (t *Tree) moveSide(side Side) bool {
+	leaf := t.LastFocusedContainer
+	target := t.findNeighbours(leaf).OnSide(side)
+	if leaf == nil || target == nil {
+		return false
+	}
+	t.remember()
+	if leaf.parent == target.parent {
+		t.swapLeaves(leaf, target)
+		return true
+	}
+
+	// Put the leaf on the side it came from
+	t.moveNextTo(leaf, target, side.direction(), ContainerModeSplit, side == SideRight || side == SideDown)
+	t.focusLeaf(leaf)
+	return true
+}
+
+// Take a leaf out of its split and put it next to the target leaf
+// The target gets replaced by a new branch with the given direction and mode containing both of them
+// If before is set, the leaf becomes the left child of the new branch, otherwise the right one
+// Expects the caller to hold the lock and the leaf to be part of a branch
+func (t *Tree) moveNextTo(leaf, target *Leaf, direction Direction, mode ContainerMode, before bool) {
+	// 1. Take the leaf out of its split, the other child takes the place of the split
+	parent := leaf.parent
+	t.replaceChild(parent.parent, Node{Type: NodeTypeBranch, Branch: parent}, parent.otherChild(leaf))
+
+	// 2. Split the target and put the leaf next to it
+	targetNode := Node{Type: NodeTypeLeaf, Leaf: target}
+	leafNode := Node{Type: NodeTypeLeaf, Leaf: leaf}
+	newBranch := Branch{
+		Direction:  direction,
+		AspectLeft: 50,
+		Mode:       mode,
+	}
+	t.replaceChild(target.parent, targetNode, Node{Type: NodeTypeBranch, Branch: &newBranch})
+	if before {
+		newBranch.setChildren(leafNode, targetNode)
+	} else {
+		newBranch.setChildren(targetNode, leafNode)
+	}
+	if !leaf.IsEmpty {
+		t.emit(Event{Type: EventWindowMoved, Window: leaf.Window})
+	}
+}
+
+// Swap the contents of two leaves and move the focus along with the first one
+// Expects the caller to hold the lock
+func (t *Tree) swapLeaves(leaf, target *Leaf) {
+	t.swapContents(leaf, target)
+	t.focusLeaf(target)
+}
+
+// The split direction that places children next to each other along a side
+func (s Side) direction() Direction {
+	if s == SideLeft || s == SideRight {
+		return DirectionHorizontal
+	}
+	return DirectionVertical
+}
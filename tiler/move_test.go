@@ -0,0 +1,65 @@
+package tiler
+
+import (
+	"testing"
+)
+
+func arrangedAppIds(tree *Tree) []string {
+	appIds := []string{}
+	for _, geometry := range tree.Arrange() {
+		appIds = append(appIds, geometry.Leaf.AppId)
+	}
+	return appIds
+}
+
+func TestSwapSide(t *testing.T) {
+	tree, leaves := threeAppTree()
+	tree.focusLeaf(leaves["c"])
+
+	if !tree.SwapSide(SideLeft) {
+		t.Fatalf("Expected c to be swapped with a")
+	}
+	if appIds := arrangedAppIds(tree); appIds[0] != "c" || appIds[1] != "b" || appIds[2] != "a" {
+		t.Errorf("Unexpected order after swap: %v", appIds)
+	}
+	if tree.LastFocusedContainer.AppId != "c" {
+		t.Errorf("Focus didn't follow c, focused is %s", tree.LastFocusedContainer.AppId)
+	}
+	if tree.FindApp(1) != leaves["c"] {
+		t.Errorf("Name lookup not updated after swap")
+	}
+	if err := checkNode(&tree.Root, nil); err != nil {
+		t.Errorf("Invalid tree structure: %s", err)
+	}
+}
+
+// Moving out of a nested split re-parents the leaf next to the neighbour
+func TestMoveSideReparent(t *testing.T) {
+	tree, leaves := threeAppTree()
+	tree.focusLeaf(leaves["c"])
+
+	if !tree.MoveSide(SideLeft) {
+		t.Fatalf("Expected c to be moved")
+	}
+	if appIds := arrangedAppIds(tree); appIds[0] != "a" || appIds[1] != "c" || appIds[2] != "b" {
+		t.Errorf("Unexpected order after move: %v", appIds)
+	}
+	if tree.LastFocusedContainer != leaves["c"] {
+		t.Errorf("Focus didn't stay on c")
+	}
+	if tree.LastFocusedParent == nil || tree.LastFocusedParent.Direction != DirectionHorizontal {
+		t.Errorf("c not placed in a horizontal split")
+	}
+	if err := checkNode(&tree.Root, nil); err != nil {
+		t.Errorf("Invalid tree structure: %s", err)
+	}
+	if tree.FindApp(2) != leaves["b"] {
+		t.Errorf("Name lookup broken after move")
+	}
+
+	// Nothing further to the left
+	tree.focusLeaf(leaves["a"])
+	if tree.MoveSide(SideLeft) {
+		t.Errorf("Moved a despite it being at the edge")
+	}
+}
@@ -0,0 +1,112 @@
+package tiler
+
+import (
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Share of the space new windows get if a preselection doesn't say otherwise
+const DEFAULT_PRESELECTION_RATIO = 50
+
+// Where the next window added next to a leaf goes
+type Preselection struct {
+	Side  Side // Side of the leaf the new window is placed on
+	Ratio int  // Percentage of the leaf's space the new window gets
+}
+
+// Choose whether split containers stay on the left/top and new windows go to the right/bottom
+// Only used while the split container isn't preselected
+func (t *Tree) SetSplitToLeft(left bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.splitToRight = !left
+}
+
+// Preselect the side of the last focused container the next added window goes to and how much of the space it gets
+// A ratio of 0 uses DEFAULT_PRESELECTION_RATIO. Only one container can be preselected at a time,
+// preselecting another one cancels the previous preselection
+// Returns false if there is no focused container or the tree is using an automatic layout
+func (t *Tree) Preselect(side Side, ratio int) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	leaf := t.LastFocusedContainer
+	if leaf == nil || t.layout != nil {
+		return false
+	}
+	if ratio == 0 {
+		ratio = DEFAULT_PRESELECTION_RATIO
+	}
+	t.cancelPreselection()
+	leaf.preselection = &Preselection{
+		Side:  side,
+		Ratio: min(max(ratio, MIN_ASPECT), MAX_ASPECT),
+	}
+	return true
+}
+
+// Cancel the preselection, so the next window is added the usual way again
+// Returns false if nothing was preselected
+func (t *Tree) CancelPreselection() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.cancelPreselection()
+}
+
+// Same as CancelPreselection, but expects the caller to already hold the lock
+func (t *Tree) cancelPreselection() bool {
+	leaf := t.preselectedLeaf()
+	if leaf == nil {
+		return false
+	}
+	leaf.preselection = nil
+	return true
+}
+
+// Get the area the next window would get because of the preselection, to show a preview of it
+// Returns false if nothing is preselected or the preselected container isn't visible
+func (t *Tree) PreselectionArea() (generaldata.Rect, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	leaf := t.preselectedLeaf()
+	if leaf == nil || t.layout != nil {
+		return generaldata.Rect{}, false
+	}
+	for _, geometry := range t.arrange(true) {
+		if geometry.Leaf != leaf || !geometry.Visible {
+			continue
+		}
+		branch, containerFirst := leaf.preselection.branch()
+		left, right := branch.splitArea(geometry.Area, nil)
+		if containerFirst {
+			return right, true
+		}
+		return left, true
+	}
+	return generaldata.Rect{}, false
+}
+
+// Get the branch splitting a preselected container, without children
+// Also returns whether the container is the left child of it
+func (p *Preselection) branch() (Branch, bool) {
+	containerFirst := p.Side == SideRight || p.Side == SideDown
+	branch := Branch{Direction: p.Side.direction(), AspectLeft: p.Ratio}
+	if containerFirst {
+		branch.AspectLeft = 100 - p.Ratio
+	}
+	return branch, containerFirst
+}
+
+// Find the leaf that is preselected, if any
+// Expects the caller to hold the lock
+func (t *Tree) preselectedLeaf() *Leaf {
+	var preselected *Leaf
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		if leaf.preselection != nil {
+			preselected = leaf
+		}
+	})
+	return preselected
+}
@@ -0,0 +1,65 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func TestPreselect(t *testing.T) {
+	tree, _ := threeAppTree()
+	tree.FocusApp(1)
+
+	if !tree.Preselect(SideUp, 30) {
+		t.Fatalf("Failed to preselect")
+	}
+	if area, ok := tree.PreselectionArea(); !ok || area != rect(0, 0, 50, 30) {
+		t.Errorf("Expected a preview above a, got %+v", area)
+	}
+
+	tree.AddApp(4, "d")
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"d": rect(0, 0, 50, 30),
+		"a": rect(0, 30, 50, 70),
+		"b": rect(50, 0, 50, 50),
+		"c": rect(50, 50, 50, 50),
+	})
+	if _, ok := tree.PreselectionArea(); ok {
+		t.Errorf("Preselection wasn't used up")
+	}
+
+	// Only one preselection at a time, and it can be canceled
+	tree.Preselect(SideLeft, 0)
+	tree.FocusApp(2)
+	tree.Preselect(SideRight, 0)
+	if area, _ := tree.PreselectionArea(); area != rect(75, 0, 25, 50) {
+		t.Errorf("Expected a preview right of b, got %+v", area)
+	}
+	if !tree.CancelPreselection() || tree.CancelPreselection() {
+		t.Errorf("Expected exactly one preselection to cancel")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Invalid tree: %s", err)
+	}
+}
+
+func TestSplitToLeft(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.SetSplitToLeft(false)
+	tree.AddApp(1, "a")
+	tree.AddApp(2, "b")
+	checkAreas(t, &tree, map[string]generaldata.Rect{
+		"b": rect(0, 0, 100, 50),
+		"a": rect(0, 50, 100, 50),
+	})
+
+	// A preselection wins over the default
+	tree.FocusApp(1)
+	tree.Preselect(SideRight, 0)
+	tree.AddApp(3, "c")
+	checkAreas(t, &tree, map[string]generaldata.Rect{
+		"b": rect(0, 0, 100, 50),
+		"a": rect(0, 50, 50, 50),
+		"c": rect(50, 50, 50, 50),
+	})
+}
@@ -0,0 +1,196 @@
+package tiler
+
+import (
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+// Move the edge of a window on the given side to a new position
+// The position is along the axis of the side, relative to the tree's origin
+// Adjusts the aspect of the closest branch whose split runs along that edge
+// Returns false if the window isn't in the tree, the edge is the border of the tree itself
+// or the tree is using an automatic layout
+// Isn't added to the undo history by itself, since it is meant to follow the cursor. Call Checkpoint before dragging
+func (t *Tree) MoveEdge(window WindowID, side Side, position int) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	return t.moveEdge(window, side, position)
+}
+
+// Same as MoveEdge, but expects the caller to already hold the lock
+func (t *Tree) moveEdge(window WindowID, side Side, position int) bool {
+	leaf := t.findApp(window)
+	if leaf == nil || t.layout != nil {
+		return false
+	}
+	branch := leaf.parentSplitAt(side)
+	if branch == nil {
+		return false
+	}
+	aspect := branch.AspectLeft
+	branch.setSplitPosition(t.branchArea(branch), position)
+	t.aspectChanged(branch, aspect)
+	return true
+}
+
+// Find the window with an edge MoveEdge can move within the given distance of a position
+// The position is relative to the tree's origin, so the border of the tree never counts
+// Returns the window and its sides close to the position, or EMPTY_WINDOW_ID if there is no such edge
+// Automatic layouts have no edges to move. Inner gaps count as part of the edges around them
+func (t *Tree) EdgesAt(position generaldata.Vector2i, distance int) (WindowID, []Side) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.layout != nil {
+		return EMPTY_WINDOW_ID, nil
+	}
+	distance += t.gaps.Inner
+	for _, geometry := range t.arrange(false) {
+		if !geometry.Visible {
+			continue
+		}
+		area := geometry.Area
+		right, bottom := area.Position.X+area.Size.X, area.Position.Y+area.Size.Y
+		if position.X < area.Position.X-distance || position.X > right+distance ||
+			position.Y < area.Position.Y-distance || position.Y > bottom+distance {
+			continue
+		}
+		sides := []Side{}
+		for _, edge := range []struct {
+			side   Side
+			offset int
+		}{
+			{SideLeft, position.X - area.Position.X},
+			{SideRight, position.X - right},
+			{SideUp, position.Y - area.Position.Y},
+			{SideDown, position.Y - bottom},
+		} {
+			if max(edge.offset, -edge.offset) <= distance && geometry.Leaf.parentSplitAt(edge.side) != nil {
+				sides = append(sides, edge.side)
+			}
+		}
+		if len(sides) > 0 {
+			return geometry.Leaf.Window, sides
+		}
+	}
+	return EMPTY_WINDOW_ID, nil
+}
+
+// Move the edge of a window on the given side outwards by the given amount of pixels
+// Negative amounts move the edge inwards
+// Returns false if the window isn't in the tree, the edge is the border of the tree itself
+// or the tree is using an automatic layout
+func (t *Tree) ResizeEdge(window WindowID, side Side, pixels int) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	return t.resizeEdge(window, side, pixels)
+}
+
+// Same as ResizeEdge, but expects the caller to already hold the lock
+func (t *Tree) resizeEdge(window WindowID, side Side, pixels int) bool {
+	leaf := t.findApp(window)
+	if leaf == nil || t.layout != nil {
+		return false
+	}
+	branch := leaf.parentSplitAt(side)
+	if branch == nil {
+		return false
+	}
+	t.remember()
+	area := t.branchArea(branch)
+	left, _ := branch.splitArea(area, t.hints)
+	position := left.Position.X + left.Size.X
+	if branch.Direction == DirectionVertical {
+		position = left.Position.Y + left.Size.Y
+	}
+	if side == SideLeft || side == SideUp {
+		position -= pixels
+	} else {
+		position += pixels
+	}
+	aspect := branch.AspectLeft
+	branch.setSplitPosition(area, position)
+	t.aspectChanged(branch, aspect)
+	return true
+}
+
+// Move the edge of a window on the given side outwards by a percentage of the split containing that edge
+// Negative percentages move the edge inwards
+// Returns false if the window isn't in the tree, the edge is the border of the tree itself
+// or the tree is using an automatic layout
+func (t *Tree) ResizeEdgePercent(window WindowID, side Side, percent int) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	return t.resizeEdgePercent(window, side, percent)
+}
+
+// Same as ResizeEdgePercent, but expects the caller to already hold the lock
+func (t *Tree) resizeEdgePercent(window WindowID, side Side, percent int) bool {
+	leaf := t.findApp(window)
+	if leaf == nil || t.layout != nil {
+		return false
+	}
+	branch := leaf.parentSplitAt(side)
+	if branch == nil {
+		return false
+	}
+	t.remember()
+	if side == SideLeft || side == SideUp {
+		percent = -percent
+	}
+	aspect := branch.AspectLeft
+	branch.AspectLeft = min(max(branch.AspectLeft+percent, MIN_ASPECT), MAX_ASPECT)
+	t.aspectChanged(branch, aspect)
+	return true
+}
+
+// Announce a new aspect of a branch, unless it is the same as the old one
+// Expects the caller to hold the lock
+func (t *Tree) aspectChanged(branch *Branch, old int) {
+	if branch.AspectLeft != old {
+		t.emit(Event{Type: EventRatioChanged, Branch: branch})
+	}
+}
+
+// Find the closest ancestor whose split between its children runs along the given side of this leaf
+func (l *Leaf) parentSplitAt(side Side) *Branch {
+	// Edges on the right or bottom are the split of a branch where the leaf is in the left child
+	wantLeft := side == SideRight || side == SideDown
+	child := Node{Type: NodeTypeLeaf, Leaf: l}
+	for branch := l.parent; branch != nil; branch = branch.parent {
+		if branch.Mode == ContainerModeSplit && branch.Direction == side.direction() && sameNode(branch.ChildLeft, child) == wantLeft {
+			return branch
+		}
+		child = Node{Type: NodeTypeBranch, Branch: branch}
+	}
+	return nil
+}
+
+// Calculate the area a branch occupies within the tree
+func (t *Tree) branchArea(branch *Branch) generaldata.Rect {
+	if branch.parent == nil {
+		return t.area()
+	}
+	left, right := branch.parent.splitArea(t.branchArea(branch.parent), t.hints)
+	if branch.parent.ChildLeft.Branch == branch {
+		return left
+	}
+	return right
+}
+
+// Set the aspect so that the split between the children lies at the given position
+// The position is along the axis of the branch's direction, in the same space as area
+func (b *Branch) setSplitPosition(area generaldata.Rect, position int) {
+	start, size := area.Position.X, area.Size.X
+	if b.Direction == DirectionVertical {
+		start, size = area.Position.Y, area.Size.Y
+	}
+	if size <= 0 {
+		return
+	}
+	// Round to the closest percentage
+	aspect := ((position-start)*200 + size) / (2 * size)
+	b.AspectLeft = min(max(aspect, MIN_ASPECT), MAX_ASPECT)
+}
@@ -0,0 +1,80 @@
+package tiler
+
+import (
+	"slices"
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func TestMoveEdge(t *testing.T) {
+	tree, leaves := threeAppTree()
+	root := tree.Root.Branch
+	right := root.ChildRight.Branch
+
+	if !tree.MoveEdge(leaves["c"].Window, SideUp, 30) {
+		t.Fatalf("Failed to move top edge of c")
+	}
+	if right.AspectLeft != 30 {
+		t.Errorf("Expected right split at 30%%, got %d", right.AspectLeft)
+	}
+	// Corner drags move the outer split too
+	if !tree.MoveEdge(leaves["c"].Window, SideLeft, 70) {
+		t.Fatalf("Failed to move left edge of c")
+	}
+	if root.AspectLeft != 70 {
+		t.Errorf("Expected root split at 70%%, got %d", root.AspectLeft)
+	}
+	// Positions are relative to the tree, not the branch
+	if !tree.MoveEdge(leaves["b"].Window, SideDown, 80) || right.AspectLeft != 80 {
+		t.Errorf("Expected right split at 80%%, got %d", right.AspectLeft)
+	}
+	if tree.MoveEdge(leaves["a"].Window, SideLeft, 10) {
+		t.Errorf("Moved the border of the tree")
+	}
+	if !tree.MoveEdge(leaves["a"].Window, SideRight, -50) || root.AspectLeft != MIN_ASPECT {
+		t.Errorf("Expected aspect to be clamped to %d, got %d", MIN_ASPECT, root.AspectLeft)
+	}
+}
+
+func TestResizeEdge(t *testing.T) {
+	tree, leaves := threeAppTree()
+	root := tree.Root.Branch
+	right := root.ChildRight.Branch
+
+	if !tree.ResizeEdge(leaves["a"].Window, SideRight, 10) || root.AspectLeft != 60 {
+		t.Errorf("Expected root split at 60%%, got %d", root.AspectLeft)
+	}
+	if !tree.ResizeEdge(leaves["c"].Window, SideLeft, 20) || root.AspectLeft != 40 {
+		t.Errorf("Expected root split at 40%%, got %d", root.AspectLeft)
+	}
+	if !tree.ResizeEdgePercent(leaves["b"].Window, SideDown, -20) || right.AspectLeft != 30 {
+		t.Errorf("Expected right split at 30%%, got %d", right.AspectLeft)
+	}
+	if tree.ResizeEdge(leaves["b"].Window, SideUp, 10) {
+		t.Errorf("Resized the border of the tree")
+	}
+}
+
+// Only borders between two windows can be grabbed, not the border of the tree
+func TestEdgesAt(t *testing.T) {
+	tree, leaves := threeAppTree()
+
+	for _, test := range []struct {
+		position generaldata.Vector2i
+		window   WindowID
+		sides    []Side
+	}{
+		{generaldata.Vector2i{X: 51, Y: 10}, leaves["a"].Window, []Side{SideRight}},
+		{generaldata.Vector2i{X: 75, Y: 49}, leaves["b"].Window, []Side{SideDown}},
+		{generaldata.Vector2i{X: 51, Y: 51}, leaves["a"].Window, []Side{SideRight}},
+		{generaldata.Vector2i{X: 1, Y: 50}, EMPTY_WINDOW_ID, nil},
+		{generaldata.Vector2i{X: 75, Y: 99}, EMPTY_WINDOW_ID, nil},
+		{generaldata.Vector2i{X: 25, Y: 25}, EMPTY_WINDOW_ID, nil},
+	} {
+		window, sides := tree.EdgesAt(test.position, 2)
+		if window != test.window || !slices.Equal(sides, test.sides) {
+			t.Errorf("%+v: expected window %d with %v, got %d with %v", test.position, test.window, test.sides, window, sides)
+		}
+	}
+}
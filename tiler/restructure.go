@@ -0,0 +1,137 @@
+package tiler
+
+// Part of the tree a restructuring operation applies to
+type Scope int
+
+const (
+	ScopeParent = Scope(iota) // The branch containing the last focused container
+	ScopeRoot                 // The whole tree
+)
+
+// Rotate a part of the tree by 90 degrees
+// Returns false if there is no branch in that scope
+func (t *Tree) Rotate(scope Scope, clockwise bool) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	branch := t.scopeBranch(scope)
+	if branch == nil {
+		return false
+	}
+	t.remember()
+	branch.walkBranches(func(b *Branch) {
+		// Turning clockwise moves the left child to the top and the top child to the right
+		// so only vertical splits change their order, counter clockwise only horizontal ones
+		if (b.Direction == DirectionVertical) == clockwise {
+			b.swapChildren()
+		}
+		b.Direction = b.Direction.other()
+	})
+	return true
+}
+
+// Mirror a part of the tree along the given direction
+// Flipping horizontally swaps left and right, flipping vertically swaps top and bottom
+// Returns false if there is no branch in that scope
+func (t *Tree) Flip(scope Scope, direction Direction) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	branch := t.scopeBranch(scope)
+	if branch == nil {
+		return false
+	}
+	t.remember()
+	branch.walkBranches(func(b *Branch) {
+		if b.Direction == direction {
+			b.swapChildren()
+		}
+	})
+	return true
+}
+
+// Reset the aspects of a part of the tree so every leaf ends up with the same area
+// Each child's share of a split is weighted by the amount of leaves it contains
+// Returns false if there is no branch in that scope
+func (t *Tree) Equalize(scope Scope) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	branch := t.scopeBranch(scope)
+	if branch == nil {
+		return false
+	}
+	t.remember()
+	branch.walkBranches(func(b *Branch) {
+		left := b.ChildLeft.leafCount()
+		right := b.ChildRight.leafCount()
+		// Rounded to the closest percentage
+		aspect := b.AspectLeft
+		b.AspectLeft = (200*left + left + right) / (2 * (left + right))
+		t.aspectChanged(b, aspect)
+	})
+	return true
+}
+
+// Switch the direction of the branch containing the last focused container
+// Returns false if the last focused container isn't part of a branch
+func (t *Tree) ToggleSplit() bool {
+	t.beginChange()
+	defer t.endChange()
+
+	branch := t.scopeBranch(ScopeParent)
+	if branch == nil {
+		return false
+	}
+	t.remember()
+	branch.Direction = branch.Direction.other()
+	return true
+}
+
+// Get the branch a scope refers to
+// Expects the caller to hold the lock
+func (t *Tree) scopeBranch(scope Scope) *Branch {
+	switch scope {
+	case ScopeParent:
+		if t.LastFocusedContainer != nil {
+			return t.LastFocusedContainer.parent
+		}
+	case ScopeRoot:
+		return t.Root.Branch
+	}
+	return nil
+}
+
+// Call f for this branch and every branch below it
+func (b *Branch) walkBranches(f func(*Branch)) {
+	f(b)
+	for _, child := range []Node{b.ChildLeft, b.ChildRight} {
+		if child.Type == NodeTypeBranch {
+			child.Branch.walkBranches(f)
+		}
+	}
+}
+
+// Swap the two children of a branch without changing the space either of them gets
+func (b *Branch) swapChildren() {
+	b.setChildren(b.ChildRight, b.ChildLeft)
+	b.AspectLeft = 100 - b.AspectLeft
+	b.showRight = !b.showRight
+}
+
+// Count the leaves below a node
+// Tabbed and stacked containers only show a single leaf at a time, so they count as one
+func (n *Node) leafCount() int {
+	if n.Type == NodeTypeLeaf || n.Branch.Mode != ContainerModeSplit {
+		return 1
+	}
+	return n.Branch.ChildLeft.leafCount() + n.Branch.ChildRight.leafCount()
+}
+
+// Get the other direction
+func (d Direction) other() Direction {
+	if d == DirectionVertical {
+		return DirectionHorizontal
+	}
+	return DirectionVertical
+}
@@ -0,0 +1,103 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func checkAreas(t *testing.T, tree *Tree, expected map[string]generaldata.Rect) {
+	t.Helper()
+	for _, geometry := range tree.Arrange() {
+		if geometry.Area != expected[geometry.Leaf.AppId] {
+			t.Errorf("%s: expected %+v, got %+v", geometry.Leaf.AppId, expected[geometry.Leaf.AppId], geometry.Area)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	tree, _ := threeAppTree()
+
+	if !tree.Rotate(ScopeRoot, true) {
+		t.Fatalf("Failed to rotate tree")
+	}
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 100, 50),
+		"b": rect(50, 50, 50, 50),
+		"c": rect(0, 50, 50, 50),
+	})
+	if err := checkNode(&tree.Root, nil); err != nil {
+		t.Errorf("Invalid tree structure: %s", err)
+	}
+
+	tree.Rotate(ScopeRoot, false)
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 50, 100),
+		"b": rect(50, 0, 50, 50),
+		"c": rect(50, 50, 50, 50),
+	})
+}
+
+func TestFlip(t *testing.T) {
+	tree, leaves := threeAppTree()
+	tree.Root.Branch.AspectLeft = 30
+	tree.focusLeaf(leaves["b"])
+
+	// Only the split around b is flipped, and it isn't horizontal
+	tree.Flip(ScopeParent, DirectionHorizontal)
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 30, 100),
+		"b": rect(30, 0, 70, 50),
+		"c": rect(30, 50, 70, 50),
+	})
+
+	tree.Flip(ScopeRoot, DirectionHorizontal)
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(70, 0, 30, 100),
+		"b": rect(0, 0, 70, 50),
+		"c": rect(0, 50, 70, 50),
+	})
+
+	tree.Flip(ScopeParent, DirectionVertical)
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(70, 0, 30, 100),
+		"b": rect(0, 50, 70, 50),
+		"c": rect(0, 0, 70, 50),
+	})
+}
+
+func TestEqualize(t *testing.T) {
+	tree, leaves := threeAppTree()
+	tree.Root.Branch.AspectLeft = 80
+	leaves["b"].parent.AspectLeft = 10
+
+	if !tree.Equalize(ScopeRoot) {
+		t.Fatalf("Failed to equalize tree")
+	}
+	if aspect := tree.Root.Branch.AspectLeft; aspect != 33 {
+		t.Errorf("Expected a to get a third, got %d", aspect)
+	}
+	if aspect := leaves["b"].parent.AspectLeft; aspect != 50 {
+		t.Errorf("Expected b and c to share equally, got %d", aspect)
+	}
+}
+
+func TestToggleSplit(t *testing.T) {
+	tree, leaves := threeAppTree()
+	tree.focusLeaf(leaves["c"])
+
+	if !tree.ToggleSplit() {
+		t.Fatalf("Failed to toggle split")
+	}
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"a": rect(0, 0, 50, 100),
+		"b": rect(50, 0, 25, 100),
+		"c": rect(75, 0, 25, 100),
+	})
+
+	single := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	single.AddApp(1, "a")
+	if single.ToggleSplit() || single.Rotate(ScopeRoot, true) {
+		t.Errorf("Restructured a tree without branches")
+	}
+}
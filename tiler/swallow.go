@@ -0,0 +1,71 @@
+package tiler
+
+import "slices"
+
+// Put a new window into the leaf of a window already in the tree, hiding that one
+// The swallowed window gets its leaf back once the new window is removed again
+// Used for programs started from a terminal, so they take the place of the terminal
+// Returns false if the swallowed window isn't in the tree or the new one already is
+func (t *Tree) Swallow(swallowed, window WindowID, appId string) bool {
+	t.beginChange()
+	defer t.endChange()
+
+	leaf := t.findApp(swallowed)
+	if leaf == nil || t.findApp(window) != nil {
+		return false
+	}
+	previous := *leaf
+	previous.parent = nil
+	delete(t.leaves, swallowed)
+	t.emit(Event{Type: EventWindowRemoved, Window: swallowed})
+
+	leaf.swallowed = &previous
+	leaf.Marks = nil
+	t.fillLeaf(leaf, window, appId)
+	return true
+}
+
+// Get the window hidden by the given one, if it swallowed any
+// Returns EMPTY_WINDOW_ID if the window isn't in the tree or didn't swallow another window
+func (t *Tree) SwallowedBy(window WindowID) WindowID {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	leaf := t.findApp(window)
+	if leaf == nil || leaf.swallowed == nil {
+		return EMPTY_WINDOW_ID
+	}
+	return leaf.swallowed.Window
+}
+
+// Put the swallowed window of a leaf back into it
+// Marks that were given to other windows in the meantime stay with those
+// Expects the caller to hold the lock and the leaf to be taken out of the index already
+func (t *Tree) unswallow(leaf *Leaf) {
+	previous := leaf.swallowed
+	leaf.Window = previous.Window
+	leaf.AppId = previous.AppId
+	leaf.swallowed = previous.swallowed
+	leaf.Marks = slices.DeleteFunc(previous.Marks, func(mark string) bool {
+		return t.findMark(mark) != nil
+	})
+	if len(leaf.Marks) == 0 {
+		leaf.Marks = nil
+	}
+	t.leaves[leaf.Window] = leaf
+	t.emit(Event{Type: EventWindowAdded, Window: leaf.Window})
+}
+
+// Drop a window that was swallowed by another one, so it doesn't come back when that one is removed
+// Expects the caller to hold the lock
+func (t *Tree) forgetSwallowed(window WindowID) {
+	for _, leaf := range t.leaves {
+		for previous := &leaf.swallowed; *previous != nil; previous = &(*previous).swallowed {
+			if (*previous).Window == window {
+				*previous = (*previous).swallowed
+				t.emit(Event{Type: EventWindowRemoved, Window: window})
+				return
+			}
+		}
+	}
+}
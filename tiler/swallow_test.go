@@ -0,0 +1,67 @@
+package tiler
+
+import (
+	"slices"
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func TestSwallow(t *testing.T) {
+	tree, leaves := threeAppTree()
+	tree.Mark(2, "term")
+
+	if !tree.Swallow(2, 4, "viewer") {
+		t.Fatalf("Failed to swallow")
+	}
+	if tree.FindApp(4) != leaves["b"] || tree.FindApp(2) != nil {
+		t.Errorf("Expected the viewer to take over the terminal's leaf")
+	}
+	if tree.SwallowedBy(4) != 2 {
+		t.Errorf("Expected the viewer to hide the terminal")
+	}
+	if tree.Swallow(2, 5, "viewer") || tree.Swallow(1, 4, "viewer") {
+		t.Errorf("Swallowed with a hidden or already tiled window")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Invalid tree: %s", err)
+	}
+
+	// The terminal comes back in place, even though other windows moved around in the meantime
+	tree.FocusApp(1)
+	tree.SwapSide(SideRight)
+	tree.RemoveApp(4, true)
+	if leaf := tree.FindApp(2); leaf == nil || leaf != leaves["a"] {
+		t.Errorf("Expected the terminal back in the viewer's leaf, got %+v", leaf)
+	}
+	if marks := tree.MarksOf(2); !slices.Equal(marks, []string{"term"}) {
+		t.Errorf("Expected the terminal to keep its marks, got %v", marks)
+	}
+	checkAreas(t, tree, map[string]generaldata.Rect{
+		"b": rect(0, 0, 50, 100),
+		"a": rect(50, 0, 50, 50),
+		"c": rect(50, 50, 50, 50),
+	})
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Invalid tree: %s", err)
+	}
+}
+
+func TestSwallowClosedTerminal(t *testing.T) {
+	tree, _ := threeAppTree()
+	tree.Swallow(2, 4, "viewer")
+	tree.Swallow(4, 5, "viewer")
+
+	// A hidden window closing on its own must not come back later
+	tree.RemoveApp(4, true)
+	if tree.SwallowedBy(5) != 2 {
+		t.Errorf("Expected the closed window to be skipped")
+	}
+	tree.RemoveApp(5, true)
+	if tree.FindApp(2) == nil || tree.FindApp(4) != nil {
+		t.Errorf("Expected only the terminal to come back")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Invalid tree: %s", err)
+	}
+}
@@ -0,0 +1,128 @@
+package tiler
+
+// Handle for applying several operations to a locked tree, see Tree.Update
+// Only valid while the function given to Update runs, using it afterwards panics
+type Transaction struct {
+	tree       *Tree     // Tree the transaction applies to. Nil once Update returned
+	before     *snapshot // Structure from before the transaction, taken when the first operation is about to change it
+	remembered bool      // Whether the structure from before the transaction is in the undo history already
+}
+
+// Apply several operations to the tree as a single change
+// The tree stays locked while apply runs, so nobody else ever sees only some of the operations applied.
+// All changes are announced to subscribers in a single notification once apply returns and they are undone together
+// Operations have to go through the transaction, calling other methods of the tree from within apply deadlocks.
+// Calling Update while apply runs panics, which also means only one goroutine may use Update at a time
+func (t *Tree) Update(apply func(tx *Transaction)) {
+	if !t.updating.CompareAndSwap(false, true) {
+		panic("tiler: Tree.Update called from within Update, use the Transaction passed to apply instead")
+	}
+	defer t.updating.Store(false)
+	t.beginChange()
+	defer t.endChange()
+
+	tx := &Transaction{tree: t}
+	t.transaction = tx
+	defer func() {
+		t.transaction = nil
+		tx.tree = nil
+	}()
+	apply(tx)
+}
+
+// Get the tree the transaction applies to, panicking if it is already over
+func (tx *Transaction) valid() *Tree {
+	if tx.tree == nil {
+		panic("tiler: Transaction used after Update returned")
+	}
+	return tx.tree
+}
+
+// Get the tree for an operation that may change it
+// The first one takes the snapshot undo goes back to, transactions that only read never copy the tree
+func (tx *Transaction) change() *Tree {
+	t := tx.valid()
+	if tx.before == nil {
+		before := t.snapshot()
+		tx.before = &before
+	}
+	return t
+}
+
+// Same as Tree.FindApp
+func (tx *Transaction) FindApp(window WindowID) *Leaf {
+	return tx.valid().findApp(window)
+}
+
+// Same as Tree.Arrange, showing the geometry with all changes made so far
+func (tx *Transaction) Arrange() []LeafGeometry {
+	return tx.valid().arrange(false)
+}
+
+// Same as Tree.AddApp
+func (tx *Transaction) AddApp(window WindowID, appId string) {
+	tx.change().addApp(window, appId)
+}
+
+// Same as Tree.FillPlaceholder
+func (tx *Transaction) FillPlaceholder(window WindowID, appId, title string) bool {
+	return tx.change().fillPlaceholder(window, appId, title)
+}
+
+// Same as Tree.RemoveApp
+func (tx *Transaction) RemoveApp(window WindowID, popParent bool) {
+	tx.change().removeApp(window, popParent)
+}
+
+// Same as Tree.SwapApp
+func (tx *Transaction) SwapApp(window1, window2 WindowID) {
+	tx.change().swapApp(window1, window2)
+}
+
+// Same as Tree.SplitLastFocusedContainer
+func (tx *Transaction) Split() *Leaf {
+	t := tx.change()
+	t.remember()
+	return t.SplitLastFocusedContainer()
+}
+
+// Same as Tree.FocusApp
+func (tx *Transaction) FocusApp(window WindowID) bool {
+	return tx.change().focusApp(window)
+}
+
+// Same as Tree.SwapSide
+func (tx *Transaction) SwapSide(side Side) bool {
+	return tx.change().swapSide(side)
+}
+
+// Same as Tree.MoveSide
+func (tx *Transaction) MoveSide(side Side) bool {
+	return tx.change().moveSide(side)
+}
+
+// Same as Tree.MoveEdge, also without remembering the structure for undo
+func (tx *Transaction) MoveEdge(window WindowID, side Side, position int) bool {
+	// Drags aren't undone on their own, so they don't need a copy of the tree either
+	return tx.valid().moveEdge(window, side, position)
+}
+
+// Same as Tree.ResizeEdge
+func (tx *Transaction) ResizeEdge(window WindowID, side Side, pixels int) bool {
+	return tx.change().resizeEdge(window, side, pixels)
+}
+
+// Same as Tree.ResizeEdgePercent
+func (tx *Transaction) ResizeEdgePercent(window WindowID, side Side, percent int) bool {
+	return tx.change().resizeEdgePercent(window, side, percent)
+}
+
+// Same as Tree.SetContainerMode
+func (tx *Transaction) SetContainerMode(mode ContainerMode) bool {
+	return tx.change().setContainerMode(mode)
+}
+
+// Same as Tree.Mark
+func (tx *Transaction) Mark(window WindowID, mark string) bool {
+	return tx.change().mark(window, mark)
+}
@@ -0,0 +1,128 @@
+package tiler
+
+import (
+	"maps"
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func TestTransaction(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	batches := recordEvents(&tree)
+
+	tree.Update(func(tx *Transaction) {
+		tx.AddApp(1, "a")
+		tx.AddApp(2, "b")
+		tx.AddApp(3, "c")
+		tx.SwapApp(1, 3)
+		tx.ResizeEdgePercent(3, SideDown, 20)
+		if tx.FindApp(2) == nil || len(tx.Arrange()) != 3 {
+			t.Errorf("Changes aren't visible within the transaction")
+		}
+	})
+	if len(*batches) != 1 {
+		t.Fatalf("Expected a single notification, got %d", len(*batches))
+	}
+	if count := countEvents((*batches)[0], EventWindowAdded); count != 3 {
+		t.Errorf("Expected all 3 additions in the notification, got %d", count)
+	}
+	if count := countEvents((*batches)[0], EventGeometryChanged); count != 3 {
+		t.Errorf("Expected a single geometry change per window, got %d", count)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Invalid tree: %s", err)
+	}
+
+	// The whole transaction is a single step in the history
+	before := windowAreas(&tree)
+	tree.Update(func(tx *Transaction) {
+		tx.SwapApp(2, 3)
+		tx.FocusApp(2)
+		tx.Split()
+		tx.MoveEdge(2, SideDown, 10)
+	})
+	if !tree.Undo() {
+		t.Fatalf("Nothing to undo")
+	}
+	if after := windowAreas(&tree); !maps.Equal(before, after) {
+		t.Errorf("Expected the whole transaction to be undone at once, got %v instead of %v", after, before)
+	}
+	// Additions aren't recorded on their own, only the structure from before the first transaction is left
+	if !tree.Undo() || tree.Undo() {
+		t.Errorf("Expected the first transaction to be a single step too")
+	}
+}
+
+// Misusing a transaction panics instead of deadlocking or changing the tree behind the back of subscribers
+func TestTransactionMisuse(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	expectPanic := func(name string, apply func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected %s to panic", name)
+			}
+		}()
+		apply()
+	}
+
+	expectPanic("nested Update", func() {
+		tree.Update(func(tx *Transaction) {
+			tree.Update(func(inner *Transaction) {})
+		})
+	})
+	var leaked *Transaction
+	tree.Update(func(tx *Transaction) {
+		leaked = tx
+	})
+	expectPanic("using a finished transaction", func() {
+		leaked.AddApp(1, "a")
+	})
+	expectPanic("reading through a finished transaction", func() {
+		leaked.FindApp(1)
+	})
+	if tree.FindApp(1) != nil {
+		t.Errorf("Finished transaction changed the tree")
+	}
+
+	// Transactions that only read don't leave anything to undo
+	tree.Update(func(tx *Transaction) {
+		tx.Arrange()
+	})
+	if tree.Undo() {
+		t.Errorf("Expected nothing to undo after a transaction without changes")
+	}
+}
+
+// Readers must never see a tree with only some operations of a transaction applied
+func TestTransactionConcurrent(t *testing.T) {
+	tree := NewTree(generaldata.Vector2i{X: 100, Y: 100})
+	tree.AddApp(1, "a")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			if count := len(tree.Arrange()); count%2 != 1 {
+				t.Errorf("Saw a half-applied transaction with %d windows", count)
+				return
+			}
+		}
+	}()
+	for i := WindowID(2); i < 200; i += 2 {
+		tree.Update(func(tx *Transaction) {
+			tx.AddApp(i, "b")
+			tx.AddApp(i+1, "c")
+		})
+	}
+	<-done
+}
+
+// Get the area of every visible window
+func windowAreas(tree *Tree) map[WindowID]generaldata.Rect {
+	areas := map[WindowID]generaldata.Rect{}
+	for _, geometry := range tree.Arrange() {
+		areas[geometry.Leaf.Window] = geometry.Area
+	}
+	return areas
+}
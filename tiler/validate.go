@@ -0,0 +1,91 @@
+package tiler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Check that the tree is internally consistent
+// Covers the structure and parent pointers, the window index and the focus pointers
+// Returns the first problem found
+func (t *Tree) Validate() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := checkNode(&t.Root, nil); err != nil {
+		return fmt.Errorf("structure: %w", err)
+	}
+	if err := t.checkIndex(); err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+	if err := t.checkFocus(); err != nil {
+		return fmt.Errorf("focus: %w", err)
+	}
+	return nil
+}
+
+// Check that the index contains exactly the non-empty leaves of the tree, that no mark is used twice
+// and that swallowed windows aren't part of the tree
+func (t *Tree) checkIndex() error {
+	var err error
+	seen := map[WindowID]bool{}
+	marks := map[string]bool{}
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		if err != nil || leaf.IsEmpty {
+			return
+		}
+		if seen[leaf.Window] {
+			err = fmt.Errorf("window %d is in the tree multiple times", leaf.Window)
+			return
+		}
+		seen[leaf.Window] = true
+		for _, mark := range leaf.Marks {
+			if marks[mark] {
+				err = fmt.Errorf("mark \"%s\" is used multiple times", mark)
+				return
+			}
+			marks[mark] = true
+		}
+		if t.leaves[leaf.Window] != leaf {
+			err = fmt.Errorf("window %d not indexed", leaf.Window)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	// Swallowed windows are hidden, so they must not show up anywhere else
+	hidden := map[WindowID]bool{}
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		for previous := leaf.swallowed; err == nil && previous != nil; previous = previous.swallowed {
+			if seen[previous.Window] || hidden[previous.Window] {
+				err = fmt.Errorf("swallowed window %d is in the tree multiple times", previous.Window)
+			}
+			hidden[previous.Window] = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(t.leaves) != len(seen) {
+		return fmt.Errorf("index has %d windows, but the tree only %d", len(t.leaves), len(seen))
+	}
+	return nil
+}
+
+// Check that the focus pointers point to a leaf in the tree and its parent
+func (t *Tree) checkFocus() error {
+	if t.LastFocusedContainer == nil {
+		return errors.New("no last focused container")
+	}
+	found := false
+	t.Root.walkLeaves(func(leaf *Leaf) {
+		found = found || leaf == t.LastFocusedContainer
+	})
+	if !found {
+		return errors.New("last focused container isn't part of the tree")
+	}
+	if t.LastFocusedParent != t.LastFocusedContainer.parent {
+		return errors.New("last focused parent isn't the parent of the last focused container")
+	}
+	return nil
+}
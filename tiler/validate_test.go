@@ -0,0 +1,129 @@
+package tiler
+
+import (
+	"testing"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+)
+
+func TestValidate(t *testing.T) {
+	tree, leaves := threeAppTree()
+	if err := tree.Validate(); err != nil {
+		t.Fatalf("Valid tree failed validation: %s", err)
+	}
+
+	delete(tree.leaves, 2)
+	if tree.Validate() == nil {
+		t.Errorf("Missing index entry not detected")
+	}
+	tree.relink()
+
+	tree.LastFocusedParent = nil
+	if tree.Validate() == nil {
+		t.Errorf("Wrong focused parent not detected")
+	}
+	tree.focusLeaf(leaves["a"])
+
+	leaves["c"].parent = tree.Root.Branch
+	if tree.Validate() == nil {
+		t.Errorf("Wrong parent pointer not detected")
+	}
+}
+
+// Amount of different operations applyOperations knows, the first byte of an operation is taken modulo this
+const OPERATION_COUNT = 22
+
+// Apply a sequence of operations encoded in bytes to a tree
+// Every operation takes two bytes, the first picks the operation and the second is its argument
+// Calls check after every operation
+func applyOperations(tree *Tree, operations []byte, check func(step int)) {
+	nextWindow := WindowID(1)
+	appIds := []string{"terminal", "browser", "editor"}
+	marks := []string{"a", "b", "c"}
+	// Map the argument onto a window that is probably in the tree
+	window := func(arg byte) WindowID {
+		return WindowID(int(arg)%int(nextWindow)) + 1
+	}
+	for i := 0; i+1 < len(operations); i += 2 {
+		arg := operations[i+1]
+		switch operations[i] % OPERATION_COUNT {
+		case 0, 1:
+			tree.AddApp(nextWindow, appIds[int(arg)%len(appIds)])
+			nextWindow++
+		case 2:
+			tree.RemoveApp(window(arg), arg%2 == 0)
+		case 3:
+			tree.SwapApp(window(arg), window(arg/2))
+		case 4:
+			tree.SplitLastFocusedContainer()
+		case 5:
+			tree.FocusApp(window(arg))
+		case 6:
+			tree.MoveFocus(Side(arg % 4))
+		case 7:
+			tree.SwapSide(Side(arg % 4))
+		case 8:
+			tree.MoveSide(Side(arg % 4))
+		case 9:
+			tree.ResizeEdge(window(arg), Side(arg%4), int(arg)-128)
+		case 10:
+			tree.SetContainerMode(ContainerMode(arg % 3))
+		case 11:
+			tree.CycleTab(arg%2 == 0)
+		case 12:
+			tree.Rotate(Scope(arg%2), arg%4 < 2)
+		case 13:
+			tree.Flip(Scope(arg%2), Direction(arg/2%2))
+		case 14:
+			tree.Equalize(Scope(arg % 2))
+		case 15:
+			if arg%2 == 0 {
+				tree.ToggleSplit()
+			} else {
+				tree.FillPlaceholder(nextWindow, appIds[int(arg)%len(appIds)], "")
+				nextWindow++
+			}
+		case 16:
+			tree.Undo()
+		case 17:
+			tree.Redo()
+		case 18:
+			tree.Mark(window(arg), marks[int(arg)%len(marks)])
+		case 19:
+			if arg%2 == 0 {
+				tree.MoveToMark(marks[int(arg/2)%len(marks)])
+			} else {
+				tree.SwapMark(marks[int(arg/2)%len(marks)])
+			}
+		case 20:
+			tree.Swallow(window(arg), nextWindow, appIds[int(arg)%len(appIds)])
+			nextWindow++
+		case 21:
+			tree.Update(func(tx *Transaction) {
+				tx.Split()
+				tx.AddApp(nextWindow, appIds[int(arg)%len(appIds)])
+				tx.SwapApp(nextWindow, window(arg))
+				tx.ResizeEdge(window(arg), Side(arg%4), int(arg)-128)
+			})
+			nextWindow++
+		}
+		check(i / 2)
+	}
+}
+
+func FuzzTreeOperations(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 1, 0, 2, 2, 1, 3, 2})
+	f.Add([]byte{0, 0, 0, 0, 10, 1, 0, 0, 11, 0, 8, 2, 2, 3})
+	f.Add([]byte{0, 0, 0, 1, 0, 2, 0, 0, 12, 1, 13, 2, 14, 1, 9, 200, 7, 3, 2, 4})
+	f.Add([]byte{0, 0, 0, 1, 0, 2, 8, 1, 2, 2, 16, 0, 16, 0, 17, 0, 0, 1, 16, 0})
+	f.Add([]byte{0, 0, 0, 1, 0, 2, 18, 0, 18, 3, 5, 1, 19, 0, 2, 0, 16, 0, 19, 1})
+	f.Add([]byte{0, 0, 0, 1, 20, 0, 20, 2, 2, 3, 16, 0, 2, 1, 17, 0, 2, 2})
+	f.Fuzz(func(t *testing.T, operations []byte) {
+		tree := NewTree(generaldata.Vector2i{X: 1920, Y: 1080})
+		applyOperations(&tree, operations, func(step int) {
+			if err := tree.Validate(); err != nil {
+				t.Fatalf("Invalid tree after operation %d (%d): %s", step, operations[step*2]%OPERATION_COUNT, err)
+			}
+		})
+	})
+}
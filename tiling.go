@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mstarongithub/way2gay/config"
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+	"github.com/mstarongithub/way2gay/tiler"
+	"github.com/sirupsen/logrus"
+	"github.com/swaywm/go-wlroots/wlroots"
+)
+
+// Distance in pixels from the border between two tiled windows within which dragging with the left button moves that border
+const BORDER_GRAB_DISTANCE = 4
+
+// Get the position of an output inside the output layout
+func (server *Server) outputPosition(output wlroots.Output) (float64, float64) {
+	// Coords converts layout coordinates into output local ones
+	// So the layout origin ends up at the negated position of the output
+	x, y := server.outputLayout.Coords(output)
+	return -x, -y
+}
+
+// Convert layout coordinates into coordinates relative to the first output
+func (server *Server) outputRelative(x, y float64) generaldata.Vector2i {
+	if len(server.outputs) > 0 {
+		offsetX, offsetY := server.outputPosition(*server.outputs[0])
+		x, y = x-offsetX, y-offsetY
+	}
+	return generaldata.Vector2i{X: int(x), Y: int(y)}
+}
+
+// Resize the tiling trees of all workspaces to the first output and re-arrange the shown one
+// Hidden workspaces get arranged once they are shown again
+// TODO: One tree per output
+func (server *Server) updateTreeResolution() {
+	if len(server.outputs) == 0 {
+		return
+	}
+	width, height := server.outputs[0].EffectiveResolution()
+	for _, workspace := range server.workspaces {
+		workspace.tree.Resolution = generaldata.Vector2i{X: width, Y: height}
+	}
+	server.arrangeTree()
+}
+
+// Move and resize all toplevels of the shown workspace to the areas the tree gives them
+// Hidden tabs stay mapped, but their scene nodes get disabled so they aren't drawn
+// Maximized and fullscreen windows keep their leaf, but get placed over the usable area or the whole output instead
+// Floating windows get placed at their own area, hidden ones stay disabled
+func (server *Server) arrangeTree() {
+	if len(server.outputs) == 0 {
+		return
+	}
+	for _, geometry := range server.tree.Arrange() {
+		window, ok := server.windows[geometry.Leaf.Window]
+		if !ok || window.state != WindowStateNormal {
+			continue
+		}
+		server.placeWindow(window, geometry.Area, geometry.Visible)
+	}
+	for _, window := range server.windows {
+		if window.workspace != server.workspace {
+			continue
+		}
+		switch window.state {
+		case WindowStateMaximized:
+			server.placeWindow(window, server.tree.UsableArea(), true)
+		case WindowStateFullscreen:
+			server.placeWindow(window, generaldata.Rect{Size: server.tree.Resolution}, true)
+		default:
+			if window.floating {
+				server.placeWindow(window, window.floatingArea, !window.hidden)
+			}
+		}
+	}
+}
+
+// Re-arrange only the windows a change of the tree of a workspace affected
+// Maximized windows get placed again too, since the usable area depends on the gaps and on how many windows there are
+func (server *Server) handleTreeEvents(workspace *Workspace, events []tiler.Event) {
+	if len(server.outputs) == 0 {
+		return
+	}
+	for _, event := range events {
+		logrus.WithFields(logrus.Fields{
+			"type":   event.Type,
+			"window": event.Window,
+		}).Debugln("Tree changed")
+		if event.Type != tiler.EventGeometryChanged {
+			continue
+		}
+		window, ok := server.windows[event.Window]
+		if !ok || window.state != WindowStateNormal || window.floating {
+			continue
+		}
+		server.placeWindow(window, event.NewArea, event.NewVisible)
+	}
+	for _, window := range server.windows {
+		if window.workspace == workspace && window.state == WindowStateMaximized {
+			server.placeWindow(window, workspace.tree.UsableArea(), true)
+		}
+	}
+	// The preselected container might have moved or the preselection got used up
+	if workspace == server.workspace {
+		server.updatePreselectionPreview()
+	}
+}
+
+// Color of the preselection preview, a translucent blue with premultiplied alpha
+var PRESELECTION_PREVIEW_COLOR = [4]float32{0.12, 0.2, 0.36, 0.4}
+
+// Show where the next window goes if a container is preselected, or hide the preview if nothing is
+func (server *Server) updatePreselectionPreview() {
+	area, ok := server.tree.PreselectionArea()
+	if !ok || len(server.outputs) == 0 {
+		if server.preselectionPreview != nil {
+			server.preselectionPreview.SetEnabled(false)
+		}
+		return
+	}
+	if server.preselectionPreview == nil {
+		preview := newSceneRect(server.overlayLayer, area.Size.X, area.Size.Y, PRESELECTION_PREVIEW_COLOR)
+		server.preselectionPreview = &preview
+	} else {
+		setSceneRectSize(*server.preselectionPreview, area.Size.X, area.Size.Y)
+	}
+	offsetX, offsetY := server.outputPosition(*server.outputs[0])
+	server.preselectionPreview.SetPosition(offsetX+float64(area.Position.X), offsetY+float64(area.Position.Y))
+	server.preselectionPreview.SetEnabled(true)
+}
+
+// Move and resize a toplevel to an area relative to the first output
+// Toplevels that can't shrink to the area are still configured with their minimum size, but cut off at the border of the area
+func (server *Server) placeWindow(window *Window, area generaldata.Rect, visible bool) {
+	offsetX, offsetY := server.outputPosition(*server.outputs[0])
+	topLevel := window.topLevel
+	node := topLevel.Base().SceneTree().Node()
+	node.SetEnabled(visible)
+	node.SetPosition(
+		offsetX+float64(area.Position.X),
+		offsetY+float64(area.Position.Y),
+	)
+	minSize := window.workspace.tree.SizeHints(window.id).Min
+	width, height := max(area.Size.X, minSize.X), max(area.Size.Y, minSize.Y)
+	topLevel.Base().TopLevelSetSize(uint32(width), uint32(height))
+	// Don't let them cover their neighbours
+	if width > area.Size.X || height > area.Size.Y {
+		setTopLevelClip(topLevel, &area.Size)
+	} else {
+		setTopLevelClip(topLevel, nil)
+	}
+}
+
+// Pass the size constraints of a window on to the tree, so its geometry respects them
+// Called when the window gets mapped and whenever it commits different limits
+func (server *Server) updateSizeHints(window *Window) {
+	hints := sizeHints(window.topLevel)
+	if hints != window.workspace.tree.SizeHints(window.id) {
+		window.workspace.tree.SetSizeHints(window.id, hints)
+	}
+}
+
+// Move the keyboard focus to the window next to the focused one
+func (server *Server) focusSide(side tiler.Side) bool {
+	leaf := server.tree.MoveFocus(side)
+	if leaf == nil {
+		return false
+	}
+	return server.focusLeaf(leaf)
+}
+
+// Give the keyboard focus to the window of a leaf the tree just focused
+func (server *Server) focusLeaf(leaf *tiler.Leaf) bool {
+	window, ok := server.windows[leaf.Window]
+	if !ok {
+		logrus.WithField("window", leaf.Window).Warnln("Tiled window without toplevel")
+		return false
+	}
+	surface := window.topLevel.Base().Surface()
+	server.focusTopLevel(&window.topLevel, &surface)
+	return true
+}
+
+// Create a layout by name, using the master-stack settings from the tiling config
+func layoutFromConfig(conf config.ConfigTiling) (tiler.Layout, error) {
+	if conf.Layout == "" {
+		return nil, nil
+	}
+	layout, err := tiler.NewLayout(conf.Layout)
+	if err != nil {
+		return nil, err
+	}
+	if masterStack, ok := layout.(*tiler.MasterStack); ok {
+		if conf.MasterCount != 0 {
+			masterStack.MasterCount = conf.MasterCount
+		}
+		if conf.MasterRatio != 0 {
+			masterStack.MasterRatio = conf.MasterRatio
+		}
+	}
+	return layout, nil
+}
+
+// Convert the gaps from the tiling config, applying the per side overrides of the outer gaps
+func gapsFromConfig(conf config.ConfigGaps) tiler.Gaps {
+	gaps := tiler.Gaps{
+		Inner:       conf.Inner,
+		OuterTop:    conf.Outer,
+		OuterRight:  conf.Outer,
+		OuterBottom: conf.Outer,
+		OuterLeft:   conf.Outer,
+		Smart:       conf.Smart,
+	}
+	for _, override := range []struct {
+		value  *int
+		target *int
+	}{
+		{conf.Top, &gaps.OuterTop},
+		{conf.Right, &gaps.OuterRight},
+		{conf.Bottom, &gaps.OuterBottom},
+		{conf.Left, &gaps.OuterLeft},
+	} {
+		if override.value != nil {
+			*override.target = *override.value
+		}
+	}
+	return gaps
+}
+
+// Add a layout saved in a file to the tree, its placeholders are filled by matching windows as they appear
+func (server *Server) appendLayoutFile(file string) error {
+	layout := tiler.LayoutNode{}
+	if err := config.ReadFile(file, &layout); err != nil {
+		return err
+	}
+	if err := server.tree.AppendLayout(layout); err != nil {
+		return fmt.Errorf("invalid layout %s: %w", file, err)
+	}
+	return nil
+}
+
+// Change the gaps of the tree
+func (server *Server) setGaps(gaps tiler.Gaps) {
+	server.tree.SetGaps(gaps)
+}
+
+// Switch the layout of the tree
+func (server *Server) setLayout(name string) error {
+	conf := server.config.Tiling
+	conf.Layout = name
+	layout, err := layoutFromConfig(conf)
+	if err != nil {
+		return err
+	}
+	server.tree.SetLayout(layout)
+	return nil
+}
+
+// Change the master area of the master-stack layout
+func (server *Server) adjustMaster(countDelta, ratioDelta int) bool {
+	return server.tree.AdjustMaster(countDelta, ratioDelta)
+}
+
+// Switch the mode of the container around the focused window
+func (server *Server) setContainerMode(mode tiler.ContainerMode) bool {
+	return server.tree.SetContainerMode(mode)
+}
+
+// Switch to the next or previous tab of the container around the focused window
+func (server *Server) cycleTab(forward bool) bool {
+	leaf := server.tree.CycleTab(forward)
+	if leaf == nil {
+		return false
+	}
+	// The new tab got enabled by the tree events before it gets focused here
+	if !leaf.IsEmpty {
+		server.focusLeaf(leaf)
+	}
+	return true
+}
+
+// Move the focused window towards the given side
+// If swap is set, it always trades places with its neighbour instead of moving into the neighbour's split
+func (server *Server) moveSide(side tiler.Side, swap bool) bool {
+	if swap {
+		return server.tree.SwapSide(side)
+	}
+	return server.tree.MoveSide(side)
+}
+
+// Get the edges of a tiled window that are closest to the cursor, one horizontal and one vertical
+func (server *Server) closestEdges(window *Window) wlroots.Edges {
+	node := window.topLevel.Base().SceneTree().Node()
+	box := window.topLevel.Base().Geometry()
+	centerX := float64(node.X()) + float64(box.Width)/2
+	centerY := float64(node.Y()) + float64(box.Height)/2
+
+	edges := wlroots.EdgeRight
+	if server.cursor.X() < centerX {
+		edges = wlroots.EdgeLeft
+	}
+	if server.cursor.Y() < centerY {
+		edges |= wlroots.EdgeTop
+	} else {
+		edges |= wlroots.EdgeBottom
+	}
+	return edges
+}
+
+// Find the tiled window whose border to another tiled window is at the given layout coordinates
+// Returns nil if there is no such border within BORDER_GRAB_DISTANCE
+func (server *Server) tiledBorderAt(lx, ly float64) (*Window, wlroots.Edges) {
+	if len(server.outputs) == 0 {
+		return nil, wlroots.EdgeNone
+	}
+	offsetX, offsetY := server.outputPosition(*server.outputs[0])
+	position := generaldata.Vector2i{X: int(math.Floor(lx - offsetX)), Y: int(math.Floor(ly - offsetY))}
+	id, sides := server.tree.EdgesAt(position, BORDER_GRAB_DISTANCE)
+	window, ok := server.windows[id]
+	if !ok {
+		return nil, wlroots.EdgeNone
+	}
+	edges := wlroots.EdgeNone
+	for _, side := range sides {
+		switch side {
+		case tiler.SideUp:
+			edges |= wlroots.EdgeTop
+		case tiler.SideDown:
+			edges |= wlroots.EdgeBottom
+		case tiler.SideLeft:
+			edges |= wlroots.EdgeLeft
+		case tiler.SideRight:
+			edges |= wlroots.EdgeRight
+		}
+	}
+	return window, edges
+}
+
+// Start dragging the given edges of a tiled window with the cursor until the button is released
+func (server *Server) beginTiledResize(window *Window, edges wlroots.Edges, button uint32) {
+	// The whole drag gets undone at once
+	server.tree.Checkpoint()
+	server.grabbedTopLevel = &window.topLevel
+	server.grabButton = button
+	server.cursorMode = CursorModeResize
+	server.resizeEdges = edges
+}
+
+// Move the splits along the grabbed edges of a tiled window to the cursor
+func (server *Server) resizeTiled(window *Window, edges wlroots.Edges) {
+	if len(server.outputs) == 0 {
+		return
+	}
+	offsetX, offsetY := server.outputPosition(*server.outputs[0])
+	x := int(server.cursor.X() - offsetX)
+	y := int(server.cursor.Y() - offsetY)
+
+	// Dragging a corner moves both edges, place the windows only once for them
+	server.tree.Update(func(tx *tiler.Transaction) {
+		if edges&wlroots.EdgeTop != 0 {
+			tx.MoveEdge(window.id, tiler.SideUp, y)
+		} else if edges&wlroots.EdgeBottom != 0 {
+			tx.MoveEdge(window.id, tiler.SideDown, y)
+		}
+		if edges&wlroots.EdgeLeft != 0 {
+			tx.MoveEdge(window.id, tiler.SideLeft, x)
+		} else if edges&wlroots.EdgeRight != 0 {
+			tx.MoveEdge(window.id, tiler.SideRight, x)
+		}
+	})
+}
+
+// Grow or shrink the focused tiled window on one side
+// The amount is in pixels or, if percent is set, in percent of the split containing that side
+func (server *Server) resizeFocused(side tiler.Side, amount int, percent bool) bool {
+	focused := server.tree.LastFocusedContainer
+	if focused == nil || focused.IsEmpty {
+		return false
+	}
+	if percent {
+		return server.tree.ResizeEdgePercent(focused.Window, side, amount)
+	}
+	return server.tree.ResizeEdge(focused.Window, side, amount)
+}
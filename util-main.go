@@ -26,14 +26,14 @@ var (
 	)
 )
 
-func utilMain(_ *config.Config) {
+func utilMain(conf *config.Config) {
 	if *help {
 		utilHelpMessage()
 		return
 	}
 
 	// Init a server, used for stuff like getting displays
-	server, err := NewServer()
+	server, err := NewServer(conf)
 	if err != nil {
 		logrus.WithError(err).Fatal("initializing server")
 	}
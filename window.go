@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+	"github.com/mstarongithub/way2gay/tiler"
+	"github.com/swaywm/go-wlroots/wlroots"
+)
+
+// How a window is shown on top of its usual placement
+type WindowState int
+
+const (
+	WindowStateNormal     = WindowState(iota)
+	WindowStateMaximized  // Fills the usable area of the output
+	WindowStateFullscreen // Covers the whole output, above everything else
+)
+
+var windowStateNames = map[WindowState]string{
+	WindowStateNormal:     "normal",
+	WindowStateMaximized:  "maximized",
+	WindowStateFullscreen: "fullscreen",
+}
+
+// A toplevel managed by the compositor
+// The ID stays the same for the toplevel's whole lifetime and is what the tiling tree refers to
+type Window struct {
+	id        tiler.WindowID
+	topLevel  wlroots.XDGTopLevel
+	state     WindowState // Tiled windows keep their leaf in the tree regardless of the state
+	workspace *Workspace  // Workspace the window is shown on, tiled windows are in its tree
+
+	floating     bool             // Floating windows aren't part of the tree and are shown above tiled ones
+	floatingArea generaldata.Rect // Area of a floating window, relative to the first output
+	hidden       bool             // Still mapped, but not shown. Used for windows in the scratchpad
+	marks        []string         // Marks of a floating window. Tiled windows keep theirs in the tree, see marksOf
+	sticky       bool             // Floating window that stays visible when switching workspaces
+	pid          int              // Process of the client owning the toplevel. 0 if unknown
+}
+
+// Register a new toplevel and hand out a fresh window ID for it
+// New windows belong to the active workspace
+func (server *Server) newWindow(topLevel wlroots.XDGTopLevel) *Window {
+	server.lastWindowID++
+	window := &Window{
+		id:        server.lastWindowID,
+		topLevel:  topLevel,
+		workspace: server.workspace,
+		pid:       clientPid(topLevel),
+	}
+	server.windows[window.id] = window
+	return window
+}
+
+// Find the window belonging to a toplevel
+// Returns nil if the toplevel isn't known
+func (server *Server) findWindow(topLevel wlroots.XDGTopLevel) *Window {
+	for _, window := range server.windows {
+		if window.topLevel == topLevel {
+			return window
+		}
+	}
+	return nil
+}
+
+// Find the window that currently has the keyboard focus
+// Returns nil if no window is focused
+func (server *Server) focusedWindow() *Window {
+	surface := server.seat.KeyboardState().FocusedSurface()
+	if surface.Nil() {
+		return nil
+	}
+	topLevel, err := surface.XDGTopLevel()
+	if err != nil {
+		return nil
+	}
+	return server.findWindow(topLevel)
+}
+
+// Change the state of a window and move it into the matching layer
+// Only one window per workspace can be fullscreen at a time, a previous one goes back to normal
+func (server *Server) setWindowState(window *Window, state WindowState) {
+	if state == WindowStateFullscreen {
+		for _, other := range server.windows {
+			if other != window && other.workspace == window.workspace && other.state == WindowStateFullscreen {
+				other.state = WindowStateNormal
+				other.topLevel.Base().SceneTree().Node().Reparent(server.windowLayer(other))
+				setTopLevelState(other.topLevel, other.state)
+			}
+		}
+	}
+	window.state = state
+	window.topLevel.Base().SceneTree().Node().Reparent(server.windowLayer(window))
+	setTopLevelState(window.topLevel, window.state)
+	server.arrangeTree()
+}
+
+// Follow the request of a client to make its toplevel fullscreen or maximized, or to leave that state again
+// Toplevels that aren't mapped yet get the state they asked for once they are
+func (server *Server) handleStateRequest(window *Window, state WindowState, requested bool) {
+	if !topLevelMapped(window.topLevel) {
+		return
+	}
+	switch {
+	case requested && window.state != state:
+		server.setWindowState(window, state)
+	case !requested && window.state == state:
+		server.setWindowState(window, WindowStateNormal)
+	default:
+		// Clients expect a configure in reply even if nothing changed
+		setTopLevelState(window.topLevel, window.state)
+	}
+}
+
+// Get the scene layer of its workspace a window belongs into
+func (server *Server) windowLayer(window *Window) wlroots.SceneTree {
+	switch {
+	case window.state == WindowStateFullscreen:
+		return window.workspace.fullscreenLayer
+	case window.state == WindowStateMaximized:
+		return window.workspace.maximizedLayer
+	case window.floating:
+		return window.workspace.floatingLayer
+	default:
+		return window.workspace.tiledLayer
+	}
+}
+
+// Switch the focused window into a state, or back to normal if it's already in that state
+// Returns false if no window is focused
+func (server *Server) toggleWindowState(state WindowState) bool {
+	window := server.focusedWindow()
+	if window == nil {
+		return false
+	}
+	if window.state == state {
+		state = WindowStateNormal
+	}
+	server.setWindowState(window, state)
+	return true
+}
+
+// Forget about a window once its toplevel is gone
+func (server *Server) destroyWindow(window *Window) {
+	server.removeFromScratchpad(window)
+	window.workspace.tree.SetSizeHints(window.id, tiler.SizeHints{})
+	delete(server.windows, window.id)
+	server.removeEmptyWorkspace(window.workspace)
+}
+
+// List all windows with their app ID, state and marks, one per line
+func (server *Server) describeWindows() string {
+	ids := slices.Sorted(maps.Keys(server.windows))
+	lines := make([]string, 0, len(ids))
+	for _, id := range ids {
+		window := server.windows[id]
+		line := fmt.Sprintf("Window %d: %s, %s, workspace %s", id, window.topLevel.AppId(), windowStateNames[window.state], window.workspace.name)
+		if window.floating {
+			line += ", floating"
+		}
+		if window.sticky {
+			line += ", sticky"
+		}
+		if marks := server.marksOf(window); len(marks) > 0 {
+			line += ", marks: " + strings.Join(marks, " ")
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "Windows: None"
+	}
+	return strings.Join(lines, "\n")
+}
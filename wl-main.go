@@ -8,7 +8,7 @@ import (
 	"github.com/swaywm/go-wlroots/wlroots"
 )
 
-func wlMain(_ *config.Config) {
+func wlMain(conf *config.Config) {
 	if *help {
 		fmt.Println("---- Help message for Way2Gay in compositor mode ----")
 		fmt.Println("\nCompositor mode is when w2g will start as a compositor")
@@ -33,7 +33,7 @@ func wlMain(_ *config.Config) {
 	})
 
 	// start the server
-	server, err := NewServer()
+	server, err := NewServer(conf)
 	if err != nil {
 		logrus.WithError(err).Fatal("initializing server")
 	}
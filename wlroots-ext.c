@@ -0,0 +1,133 @@
+// Listeners for wlroots events go-wlroots doesn't wrap yet, see wlroots-ext.go
+
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <sys/eventfd.h>
+#include <unistd.h>
+#include <wayland-server-core.h>
+#include <wlr/types/wlr_scene.h>
+#include <wlr/types/wlr_xdg_shell.h>
+
+#include "_cgo_export.h"
+
+static int handle_event_loop_wakeup(int fd, uint32_t mask, void *data) {
+	uint64_t count;
+	// Reading resets the counter, so several wakeups in a row only dispatch once
+	read(fd, &count, sizeof(count));
+	handleEventLoopWakeup();
+	return 0;
+}
+
+int add_event_loop_wakeup(struct wl_event_loop *loop) {
+	int fd = eventfd(0, EFD_CLOEXEC | EFD_NONBLOCK);
+	if (fd < 0) {
+		return -1;
+	}
+	if (wl_event_loop_add_fd(loop, fd, WL_EVENT_READABLE, handle_event_loop_wakeup, NULL) == NULL) {
+		close(fd);
+		return -1;
+	}
+	return fd;
+}
+
+void wake_event_loop(int fd) {
+	uint64_t one = 1;
+	write(fd, &one, sizeof(one));
+}
+
+void close_event_loop_wakeup(int fd) {
+	close(fd);
+}
+
+struct toplevel_state_listener {
+	struct wlr_xdg_toplevel *toplevel;
+	struct wl_listener request_fullscreen;
+	struct wl_listener request_maximize;
+	struct wl_listener destroy;
+};
+
+static void handle_request_fullscreen(struct wl_listener *listener, void *data) {
+	struct toplevel_state_listener *state = wl_container_of(listener, state, request_fullscreen);
+	handleTopLevelStateRequest(state->toplevel, true);
+}
+
+static void handle_request_maximize(struct wl_listener *listener, void *data) {
+	struct toplevel_state_listener *state = wl_container_of(listener, state, request_maximize);
+	handleTopLevelStateRequest(state->toplevel, false);
+}
+
+static void handle_destroy(struct wl_listener *listener, void *data) {
+	struct toplevel_state_listener *state = wl_container_of(listener, state, destroy);
+	handleTopLevelStateDestroy(state->toplevel);
+	wl_list_remove(&state->request_fullscreen.link);
+	wl_list_remove(&state->request_maximize.link);
+	wl_list_remove(&state->destroy.link);
+	free(state);
+}
+
+void listen_toplevel_state_requests(struct wlr_xdg_toplevel *toplevel) {
+	struct toplevel_state_listener *state = calloc(1, sizeof(*state));
+	state->toplevel = toplevel;
+	state->request_fullscreen.notify = handle_request_fullscreen;
+	wl_signal_add(&toplevel->events.request_fullscreen, &state->request_fullscreen);
+	state->request_maximize.notify = handle_request_maximize;
+	wl_signal_add(&toplevel->events.request_maximize, &state->request_maximize);
+	state->destroy.notify = handle_destroy;
+	wl_signal_add(&toplevel->base->events.destroy, &state->destroy);
+}
+
+struct toplevel_size_listener {
+	struct wlr_xdg_toplevel *toplevel;
+	// Limits the toplevel had on its last commit
+	int32_t min_width, min_height, max_width, max_height;
+	struct wl_listener commit;
+	struct wl_listener destroy;
+};
+
+static void handle_size_commit(struct wl_listener *listener, void *data) {
+	struct toplevel_size_listener *size = wl_container_of(listener, size, commit);
+	struct wlr_xdg_toplevel_state *current = &size->toplevel->current;
+	if (current->min_width == size->min_width && current->min_height == size->min_height &&
+			current->max_width == size->max_width && current->max_height == size->max_height) {
+		return;
+	}
+	size->min_width = current->min_width;
+	size->min_height = current->min_height;
+	size->max_width = current->max_width;
+	size->max_height = current->max_height;
+	handleTopLevelSizeLimits(size->toplevel);
+}
+
+static void handle_size_destroy(struct wl_listener *listener, void *data) {
+	struct toplevel_size_listener *size = wl_container_of(listener, size, destroy);
+	handleTopLevelSizeLimitsDestroy(size->toplevel);
+	wl_list_remove(&size->commit.link);
+	wl_list_remove(&size->destroy.link);
+	free(size);
+}
+
+void listen_toplevel_size_limits(struct wlr_xdg_toplevel *toplevel) {
+	struct toplevel_size_listener *size = calloc(1, sizeof(*size));
+	size->toplevel = toplevel;
+	size->commit.notify = handle_size_commit;
+	wl_signal_add(&toplevel->base->surface->events.commit, &size->commit);
+	size->destroy.notify = handle_size_destroy;
+	wl_signal_add(&toplevel->base->events.destroy, &size->destroy);
+}
+
+void set_toplevel_clip(struct wlr_xdg_toplevel *toplevel, struct wlr_scene_tree *tree, bool clip, int width, int height) {
+	// The surface of the toplevel comes first in its scene tree, popups get added after it and stay unclipped
+	struct wlr_scene_node *surface = wl_container_of(tree->children.next, surface, link);
+	if (!clip) {
+		wlr_scene_subsurface_tree_set_clip(surface, NULL);
+		return;
+	}
+	struct wlr_box box = {
+		.x = toplevel->base->current.geometry.x,
+		.y = toplevel->base->current.geometry.y,
+		.width = width,
+		.height = height,
+	};
+	wlr_scene_subsurface_tree_set_clip(surface, &box);
+}
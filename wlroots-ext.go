@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"unsafe"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+	"github.com/mstarongithub/way2gay/tiler"
+	"github.com/swaywm/go-wlroots/wlroots"
+	"github.com/swaywm/go-wlroots/xkb"
+)
+
+// Bindings for parts of wlroots go-wlroots doesn't wrap yet
+// Every wrapper type of go-wlroots is a struct holding nothing but the pointer to the wlroots object,
+// so the pointer can be taken out of and put back into the wrapper without a fork of go-wlroots
+
+// #cgo pkg-config: wlroots wayland-server xkbcommon
+// #cgo CFLAGS: -D_GNU_SOURCE -DWLR_USE_UNSTABLE
+// #include <wayland-server-core.h>
+// #include <wlr/types/wlr_scene.h>
+// #include <wlr/types/wlr_seat.h>
+// #include <wlr/types/wlr_xdg_shell.h>
+// #include <xkbcommon/xkbcommon.h>
+//
+// int add_event_loop_wakeup(struct wl_event_loop *loop);
+// void wake_event_loop(int fd);
+// void close_event_loop_wakeup(int fd);
+// void listen_toplevel_state_requests(struct wlr_xdg_toplevel *toplevel);
+// void listen_toplevel_size_limits(struct wlr_xdg_toplevel *toplevel);
+// void set_toplevel_clip(struct wlr_xdg_toplevel *toplevel, struct wlr_scene_tree *tree, bool clip, int width, int height);
+import "C"
+
+func eventLoopPointer(loop wlroots.EventLoop) *C.struct_wl_event_loop {
+	return *(**C.struct_wl_event_loop)(unsafe.Pointer(&loop))
+}
+
+func xdgTopLevelPointer(topLevel wlroots.XDGTopLevel) *C.struct_wlr_xdg_toplevel {
+	return *(**C.struct_wlr_xdg_toplevel)(unsafe.Pointer(&topLevel))
+}
+
+func sceneTreePointer(tree wlroots.SceneTree) *C.struct_wlr_scene_tree {
+	return *(**C.struct_wlr_scene_tree)(unsafe.Pointer(&tree))
+}
+
+func sceneNodePointer(node wlroots.SceneNode) *C.struct_wlr_scene_node {
+	return *(**C.struct_wlr_scene_node)(unsafe.Pointer(&node))
+}
+
+func wrapSceneNode(node *C.struct_wlr_scene_node) wlroots.SceneNode {
+	return *(*wlroots.SceneNode)(unsafe.Pointer(&node))
+}
+
+func seatPointer(seat wlroots.Seat) *C.struct_wlr_seat {
+	return *(**C.struct_wlr_seat)(unsafe.Pointer(&seat))
+}
+
+func xkbStatePointer(state xkb.State) *C.struct_xkb_state {
+	return *(**C.struct_xkb_state)(unsafe.Pointer(&state))
+}
+
+// Function called on the event loop after wakeEventLoop, there is only one event loop to wake
+var eventLoopWakeupHandler func()
+
+// Call a function on the event loop every time wakeEventLoop is called
+// Returns the file descriptor to pass to wakeEventLoop and closeEventLoopWakeup
+func onEventLoopWakeup(loop wlroots.EventLoop, handler func()) (int, error) {
+	fd := C.add_event_loop_wakeup(eventLoopPointer(loop))
+	if fd < 0 {
+		return -1, errors.New("can't add a wakeup source to the event loop")
+	}
+	eventLoopWakeupHandler = handler
+	return int(fd), nil
+}
+
+// Make the event loop call the wakeup handler, safe to call from any goroutine
+// Wakeups piling up before the event loop gets to them only call the handler once
+func wakeEventLoop(fd int) {
+	C.wake_event_loop(C.int(fd))
+}
+
+// Close the file descriptor of onEventLoopWakeup once the event loop is gone
+func closeEventLoopWakeup(fd int) {
+	C.close_event_loop_wakeup(C.int(fd))
+}
+
+//export handleEventLoopWakeup
+func handleEventLoopWakeup() {
+	if eventLoopWakeupHandler != nil {
+		eventLoopWakeupHandler()
+	}
+}
+
+// Get the keysym a key produces without any modifiers, in the layout that is active
+// Returns KeySymNoSymbol if the key doesn't produce any
+func unmodifiedKeySym(state xkb.State, keyCode xkb.KeyCode) xkb.KeySym {
+	pointer := xkbStatePointer(state)
+	layout := C.xkb_state_key_get_layout(pointer, C.xkb_keycode_t(keyCode))
+	var syms *C.xkb_keysym_t
+	if C.xkb_keymap_key_get_syms_by_level(C.xkb_state_get_keymap(pointer), C.xkb_keycode_t(keyCode), layout, 0, &syms) == 0 {
+		return xkb.KeySymNoSymbol
+	}
+	return xkb.KeySym(*syms)
+}
+
+// Functions called when a toplevel asks for a state, by toplevel
+var topLevelStateRequestHandlers = map[*C.struct_wlr_xdg_toplevel]func(state WindowState, requested bool){}
+
+// Call a function whenever a toplevel asks to become fullscreen or maximized, or to stop being so
+// The function gets the state asked about and whether the client wants it or wants to leave it
+func onTopLevelStateRequest(topLevel wlroots.XDGTopLevel, handler func(state WindowState, requested bool)) {
+	pointer := xdgTopLevelPointer(topLevel)
+	topLevelStateRequestHandlers[pointer] = handler
+	C.listen_toplevel_state_requests(pointer)
+}
+
+//export handleTopLevelStateRequest
+func handleTopLevelStateRequest(topLevel *C.struct_wlr_xdg_toplevel, fullscreen C.bool) {
+	handler, ok := topLevelStateRequestHandlers[topLevel]
+	if !ok {
+		return
+	}
+	if fullscreen {
+		handler(WindowStateFullscreen, bool(topLevel.requested.fullscreen))
+	} else {
+		handler(WindowStateMaximized, bool(topLevel.requested.maximized))
+	}
+}
+
+//export handleTopLevelStateDestroy
+func handleTopLevelStateDestroy(topLevel *C.struct_wlr_xdg_toplevel) {
+	delete(topLevelStateRequestHandlers, topLevel)
+}
+
+// Get the state a toplevel asked to be in so far, fullscreen winning over maximized
+func requestedWindowState(topLevel wlroots.XDGTopLevel) WindowState {
+	requested := xdgTopLevelPointer(topLevel).requested
+	switch {
+	case bool(requested.fullscreen):
+		return WindowStateFullscreen
+	case bool(requested.maximized):
+		return WindowStateMaximized
+	default:
+		return WindowStateNormal
+	}
+}
+
+// Tell the client of a toplevel which state it is in
+// This always sends a configure, which also answers requests that didn't change anything
+func setTopLevelState(topLevel wlroots.XDGTopLevel, state WindowState) {
+	pointer := xdgTopLevelPointer(topLevel)
+	C.wlr_xdg_toplevel_set_fullscreen(pointer, C.bool(state == WindowStateFullscreen))
+	C.wlr_xdg_toplevel_set_maximized(pointer, C.bool(state == WindowStateMaximized))
+}
+
+// Get whether the surface of a toplevel is mapped
+func topLevelMapped(topLevel wlroots.XDGTopLevel) bool {
+	return bool(xdgTopLevelPointer(topLevel).base.surface.mapped)
+}
+
+// Get the process ID of the client owning a toplevel
+// Returns 0 if it can't be found out
+func clientPid(topLevel wlroots.XDGTopLevel) int {
+	resource := xdgTopLevelPointer(topLevel).resource
+	if resource == nil {
+		return 0
+	}
+	var pid C.pid_t
+	C.wl_client_get_credentials(C.wl_resource_get_client(resource), &pid, nil, nil)
+	return int(pid)
+}
+
+// Get the min and max size a toplevel asked for
+// 0 means there is no limit in that direction
+func sizeHints(topLevel wlroots.XDGTopLevel) tiler.SizeHints {
+	current := xdgTopLevelPointer(topLevel).current
+	return tiler.SizeHints{
+		Min: generaldata.Vector2i{X: int(current.min_width), Y: int(current.min_height)},
+		Max: generaldata.Vector2i{X: int(current.max_width), Y: int(current.max_height)},
+	}
+}
+
+// Functions called when a toplevel commits different size limits, by toplevel
+var topLevelSizeLimitsHandlers = map[*C.struct_wlr_xdg_toplevel]func(){}
+
+// Call a function whenever a toplevel commits a min or max size that differs from its previous one
+func onTopLevelSizeLimits(topLevel wlroots.XDGTopLevel, handler func()) {
+	pointer := xdgTopLevelPointer(topLevel)
+	topLevelSizeLimitsHandlers[pointer] = handler
+	C.listen_toplevel_size_limits(pointer)
+}
+
+//export handleTopLevelSizeLimits
+func handleTopLevelSizeLimits(topLevel *C.struct_wlr_xdg_toplevel) {
+	if handler, ok := topLevelSizeLimitsHandlers[topLevel]; ok {
+		handler()
+	}
+}
+
+//export handleTopLevelSizeLimitsDestroy
+func handleTopLevelSizeLimitsDestroy(topLevel *C.struct_wlr_xdg_toplevel) {
+	delete(topLevelSizeLimitsHandlers, topLevel)
+}
+
+// Cut a toplevel off at a size, starting at the top left corner of its window geometry
+// A nil size shows all of it again
+func setTopLevelClip(topLevel wlroots.XDGTopLevel, size *generaldata.Vector2i) {
+	tree := sceneTreePointer(topLevel.Base().SceneTree())
+	if size == nil {
+		C.set_toplevel_clip(xdgTopLevelPointer(topLevel), tree, false, 0, 0)
+		return
+	}
+	C.set_toplevel_clip(xdgTopLevelPointer(topLevel), tree, true, C.int(size.X), C.int(size.Y))
+}
+
+// Create a rect filled with a single color in a scene tree
+// The color is RGBA with premultiplied alpha
+func newSceneRect(parent wlroots.SceneTree, width, height int, color [4]float32) wlroots.SceneNode {
+	cColor := [4]C.float{C.float(color[0]), C.float(color[1]), C.float(color[2]), C.float(color[3])}
+	rect := C.wlr_scene_rect_create(sceneTreePointer(parent), C.int(width), C.int(height), &cColor[0])
+	return wrapSceneNode(&rect.node)
+}
+
+// Change the size of a node created with newSceneRect
+func setSceneRectSize(node wlroots.SceneNode, width, height int) {
+	C.wlr_scene_rect_set_size(C.wlr_scene_rect_from_node(sceneNodePointer(node)), C.int(width), C.int(height))
+}
+
+// Take the keyboard focus away from whichever surface has it, so no client gets the keys
+func clearKeyboardFocus(seat wlroots.Seat) {
+	C.wlr_seat_keyboard_notify_clear_focus(seatPointer(seat))
+}
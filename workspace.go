@@ -0,0 +1,212 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	generaldata "github.com/mstarongithub/way2gay/general-data"
+	"github.com/mstarongithub/way2gay/tiler"
+	"github.com/sirupsen/logrus"
+	"github.com/swaywm/go-wlroots/wlroots"
+)
+
+// Workspace shown on start
+const FIRST_WORKSPACE = "1"
+
+// A set of windows shown together, with its own tiling tree
+// Only the active workspace is shown. The scene trees of the others are disabled, their clients stay mapped
+type Workspace struct {
+	name  string            // Numbered workspaces are named after their number
+	tree  tiler.Tree        // Tiling layout of the workspace on the first output
+	scene wlroots.SceneTree // Parent of the layers, disabled while the workspace is hidden
+	// Layers toplevels get sorted into, later layers are shown above earlier ones
+	tiledLayer      wlroots.SceneTree
+	maximizedLayer  wlroots.SceneTree
+	floatingLayer   wlroots.SceneTree
+	fullscreenLayer wlroots.SceneTree
+}
+
+// Get a workspace by name, creating it if it doesn't exist yet
+// New workspaces start out hidden, with the layout and gaps from the config
+func (server *Server) getWorkspace(name string) *Workspace {
+	if workspace, ok := server.workspaces[name]; ok {
+		return workspace
+	}
+	workspace := &Workspace{name: name}
+	workspace.scene = server.workspaceLayer.NewSceneTree()
+	workspace.scene.Node().SetEnabled(false)
+	workspace.tiledLayer = workspace.scene.NewSceneTree()
+	workspace.maximizedLayer = workspace.scene.NewSceneTree()
+	workspace.floatingLayer = workspace.scene.NewSceneTree()
+	workspace.fullscreenLayer = workspace.scene.NewSceneTree()
+
+	conf := server.config.Tiling
+	resolution := generaldata.Vector2i{}
+	if server.tree != nil {
+		resolution = server.tree.Resolution
+	}
+	workspace.tree = tiler.NewTree(resolution)
+	if layout, err := layoutFromConfig(conf); err != nil {
+		logrus.WithError(err).Warnln("Invalid layout in config, using tree")
+	} else {
+		workspace.tree.SetLayout(layout)
+	}
+	workspace.tree.SetGaps(gapsFromConfig(conf.Gaps))
+	workspace.tree.SetSplitToLeft(conf.SplitToLeft == nil || *conf.SplitToLeft)
+	workspace.tree.Subscribe(func(events []tiler.Event) {
+		server.handleTreeEvents(workspace, events)
+	})
+	server.workspaces[name] = workspace
+	return workspace
+}
+
+// Show a workspace in place of the active one, which gets hidden
+// Sticky windows move along to the shown workspace. The keyboard focus is left alone
+func (server *Server) showWorkspace(workspace *Workspace) {
+	previous := server.workspace
+	if previous == workspace {
+		return
+	}
+	server.workspace = workspace
+	server.tree = &workspace.tree
+	workspace.scene.Node().SetEnabled(true)
+	server.moveStickyWindows()
+	server.arrangeTree()
+	server.updatePreselectionPreview()
+	if previous != nil {
+		previous.scene.Node().SetEnabled(false)
+		server.previousWorkspace = previous.name
+		server.removeEmptyWorkspace(previous)
+	}
+}
+
+// Switch to a workspace by name and focus the window that was focused last on it
+// "back-and-forth" switches to the previously active workspace
+// Returns false if the workspace is active already
+func (server *Server) switchWorkspace(name string) bool {
+	workspace := server.getWorkspace(server.resolveWorkspace(name))
+	if workspace == server.workspace {
+		return false
+	}
+	server.showWorkspace(workspace)
+	server.focusWorkspace()
+	return true
+}
+
+// Give the keyboard focus to the window focused last on the active workspace
+// If there is none, the focused window is deactivated and loses the keyboard in case it isn't shown anymore
+func (server *Server) focusWorkspace() {
+	if leaf := server.tree.LastFocusedContainer; leaf != nil && !leaf.IsEmpty {
+		server.focusLeaf(leaf)
+		return
+	}
+	if window := server.focusedWindow(); window != nil && window.workspace != server.workspace {
+		window.topLevel.SetActivated(false)
+		clearKeyboardFocus(server.seat)
+	}
+}
+
+// Move a window to another workspace, keeping its marks and size hints
+// Tiled windows get added to the tree of the target workspace like new windows, fullscreen and maximized ones go back to normal
+// Sticky windows stay on the target workspace from now on
+// Returns false if the window is on that workspace already
+func (server *Server) moveToWorkspace(window *Window, name string) bool {
+	source := window.workspace
+	target := server.getWorkspace(server.resolveWorkspace(name))
+	if target == source {
+		return false
+	}
+	if window.state != WindowStateNormal {
+		server.setWindowState(window, WindowStateNormal)
+	}
+	server.setSticky(window, false)
+	if !window.floating {
+		marks := server.marksOf(window)
+		hints := source.tree.SizeHints(window.id)
+		source.tree.RemoveApp(window.id, true)
+		source.tree.SetSizeHints(window.id, tiler.SizeHints{})
+		target.tree.SetSizeHints(window.id, hints)
+		target.tree.Update(func(tx *tiler.Transaction) {
+			tx.AddApp(window.id, window.topLevel.AppId())
+			for _, mark := range marks {
+				tx.Mark(window.id, mark)
+			}
+		})
+	}
+	window.workspace = target
+	window.topLevel.Base().SceneTree().Node().Reparent(server.windowLayer(window))
+
+	// The window left the active workspace, hand the focus to the next one there
+	if source == server.workspace {
+		server.focusWorkspace()
+	}
+	server.removeEmptyWorkspace(source)
+	return true
+}
+
+// Get the name of the workspace a command refers to
+// "back-and-forth" is the previously active workspace, or the active one if there was none
+func (server *Server) resolveWorkspace(name string) string {
+	if name != "back-and-forth" {
+		return name
+	}
+	if server.previousWorkspace == "" {
+		return server.workspace.name
+	}
+	return server.previousWorkspace
+}
+
+// Forget about a hidden workspace that has neither windows nor placeholders left
+// It gets created again from the config when switching to it
+func (server *Server) removeEmptyWorkspace(workspace *Workspace) {
+	if workspace == server.workspace || !workspace.tree.IsEmpty() {
+		return
+	}
+	for _, window := range server.windows {
+		if window.workspace == workspace {
+			return
+		}
+	}
+	workspace.scene.Node().Destroy()
+	delete(server.workspaces, workspace.name)
+}
+
+// List the names of all workspaces, numbered ones first in their order and named ones after them
+func (server *Server) workspaceNames() []string {
+	return slices.SortedFunc(maps.Keys(server.workspaces), func(a, b string) int {
+		numberA, errA := strconv.Atoi(a)
+		numberB, errB := strconv.Atoi(b)
+		switch {
+		case errA == nil && errB == nil:
+			return cmp.Compare(numberA, numberB)
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			return strings.Compare(a, b)
+		}
+	})
+}
+
+// List all workspaces with their amount of windows, marking the active one
+func (server *Server) describeWorkspaces() string {
+	counts := map[*Workspace]int{}
+	for _, window := range server.windows {
+		counts[window.workspace]++
+	}
+	lines := []string{}
+	for _, name := range server.workspaceNames() {
+		workspace := server.workspaces[name]
+		line := fmt.Sprintf("Workspace %s: %d windows", name, counts[workspace])
+		if workspace == server.workspace {
+			line += ", active"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}